@@ -0,0 +1,368 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package TTObserver
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	s "sot-te.ch/TTObserverV1/shared"
+)
+
+const (
+	apiDefaultLimit = 20
+	apiMaxLimit     = 100
+)
+
+var errUnauthorized = errors.New("unauthorized")
+
+type apiRelease struct {
+	Id    int64             `json:"id"`
+	Name  string            `json:"name"`
+	URL   string            `json:"url,omitempty"`
+	Size  uint64            `json:"size,omitempty"`
+	Meta  map[string]string `json:"meta,omitempty"`
+	Files []string          `json:"files,omitempty"`
+}
+
+type apiCrawler struct {
+	Offset uint `json:"offset"`
+	Paused bool `json:"paused"`
+}
+
+type apiAnnounce struct {
+	Producers []string `json:"producers"`
+}
+
+type apiDelivery struct {
+	Release   int64     `json:"release"`
+	Revision  time.Time `json:"revision"`
+	Producer  string    `json:"producer"`
+	Target    string    `json:"target"`
+	MessageId string    `json:"messageid,omitempty"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+type apiJob struct {
+	Id       int64     `json:"id"`
+	Release  int64     `json:"release"`
+	Producer string    `json:"producer"`
+	IsNew    bool      `json:"isnew"`
+	Created  time.Time `json:"created"`
+	Attempts uint      `json:"attempts"`
+	Error    string    `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warning(err)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errUnauthorized):
+		status = http.StatusUnauthorized
+	case errors.Is(err, s.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, s.ErrRequiredParameters):
+		status = http.StatusBadRequest
+	case errors.Is(err, errNotInitialized):
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (cr *Observer) authorized(r *http.Request) bool {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if found && len(token) > 0 {
+		for _, t := range cr.API.Tokens {
+			if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (cr *Observer) apiHandler(fn func(r *http.Request, db s.Database) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cr.authorized(r) {
+			logger.Info("Unauthorized API request from ", r.RemoteAddr)
+			writeError(w, errUnauthorized)
+			return
+		}
+		db, _, err := cr.state()
+		var res any
+		if err == nil {
+			res, err = fn(r, db)
+		}
+		if err == nil {
+			if res == nil {
+				w.WriteHeader(http.StatusNoContent)
+			} else {
+				writeJSON(w, http.StatusOK, res)
+			}
+		} else {
+			writeError(w, err)
+		}
+	}
+}
+
+func pathId(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		err = s.ErrRequiredParameters
+	}
+	return id, err
+}
+
+func queryUint(r *http.Request, name string, def uint) uint {
+	if v, err := strconv.ParseUint(r.URL.Query().Get(name), 10, 32); err == nil {
+		return uint(v)
+	}
+	return def
+}
+
+func (cr *Observer) listReleases(r *http.Request, db s.Database) (any, error) {
+	query := r.URL.Query().Get("q")
+	if len(query) == 0 {
+		query = "%"
+	}
+	limit := queryUint(r, "limit", apiDefaultLimit)
+	if limit == 0 || limit > apiMaxLimit {
+		limit = apiMaxLimit
+	}
+	torrents, err := db.SearchTorrents(query, queryUint(r, "offset", 0), limit)
+	res := make([]apiRelease, 0, len(torrents))
+	for _, t := range torrents {
+		res = append(res, apiRelease{Id: t.Id, Name: t.Name, URL: t.URL})
+	}
+	return res, err
+}
+
+func (cr *Observer) getRelease(r *http.Request, _ s.Database) (any, error) {
+	id, err := pathId(r)
+	if err != nil {
+		return nil, err
+	}
+	var torrent *s.TorrentInfo
+	if torrent, err = cr.LoadTorrent(id); err != nil {
+		return nil, err
+	}
+	res := apiRelease{
+		Id:    torrent.Id,
+		Name:  torrent.Name,
+		URL:   torrent.URL,
+		Size:  torrent.Length,
+		Meta:  torrent.Meta,
+		Files: make([]string, 0, len(torrent.Files)),
+	}
+	for f := range torrent.Files {
+		res.Files = append(res.Files, f)
+	}
+	return res, nil
+}
+
+func (cr *Observer) getPoster(w http.ResponseWriter, r *http.Request) {
+	if !cr.authorized(r) {
+		writeError(w, errUnauthorized)
+		return
+	}
+	db, _, err := cr.state()
+	var id int64
+	if err == nil {
+		id, err = pathId(r)
+	}
+	var image []byte
+	if err == nil {
+		if image, err = db.GetTorrentImage(id); err == nil && len(image) == 0 {
+			err = s.ErrNotFound
+		}
+	}
+	if err == nil {
+		w.Header().Set("Content-Type", http.DetectContentType(image))
+		_, _ = w.Write(image)
+	} else {
+		writeError(w, err)
+	}
+}
+
+func (cr *Observer) announceRelease(r *http.Request, _ s.Database) (any, error) {
+	id, err := pathId(r)
+	if err == nil {
+		req := new(apiAnnounce)
+		if r.ContentLength != 0 {
+			err = decodeBody(r, req)
+		}
+		if err == nil {
+			err = cr.Reannounce(id, req.Producers)
+		}
+	}
+	return nil, err
+}
+
+func idList(ids []int64, err error) (any, error) {
+	if ids == nil {
+		ids = []int64{}
+	}
+	return ids, err
+}
+
+func decodeBody(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", s.ErrRequiredParameters, err)
+	}
+	return nil
+}
+
+func modifyId(modify func(s.Database, int64) error) func(*http.Request, s.Database) (any, error) {
+	return func(r *http.Request, db s.Database) (any, error) {
+		id, err := pathId(r)
+		if err == nil {
+			err = modify(db, id)
+		}
+		return nil, err
+	}
+}
+
+func (cr *Observer) getCrawler(_ *http.Request, db s.Database) (any, error) {
+	offset, err := db.GetCrawlOffset()
+	return apiCrawler{Offset: offset, Paused: cr.Paused()}, err
+}
+
+func (cr *Observer) setOffset(r *http.Request, _ s.Database) (any, error) {
+	req := new(apiCrawler)
+	err := decodeBody(r, req)
+	if err == nil {
+		err = cr.SetOffset(req.Offset)
+	}
+	return nil, err
+}
+
+func (cr *Observer) listDeliveries(r *http.Request, db s.Database) (any, error) {
+	id, err := pathId(r)
+	if err != nil {
+		return nil, err
+	}
+	var records []s.DeliveryRecord
+	records, err = db.GetDeliveryRecords(id)
+	res := make([]apiDelivery, 0, len(records))
+	for _, d := range records {
+		res = append(res, apiDelivery{
+			Release:   d.Torrent,
+			Revision:  d.Revision,
+			Producer:  d.Producer,
+			Target:    d.Target,
+			MessageId: d.MessageId,
+			Status:    d.Status,
+			Error:     d.Error,
+			Time:      d.Time,
+		})
+	}
+	return res, err
+}
+
+func (cr *Observer) listDead(r *http.Request, db s.Database) (any, error) {
+	limit := queryUint(r, "limit", apiDefaultLimit)
+	if limit == 0 || limit > apiMaxLimit {
+		limit = apiMaxLimit
+	}
+	jobs, err := db.GetDeadOutboxJobs(queryUint(r, "offset", 0), limit)
+	res := make([]apiJob, 0, len(jobs))
+	for _, j := range jobs {
+		res = append(res, apiJob{
+			Id:       j.Id,
+			Release:  j.Torrent,
+			Producer: j.Producer,
+			IsNew:    j.IsNew,
+			Created:  j.Created,
+			Attempts: j.Attempts,
+			Error:    j.Error,
+		})
+	}
+	return res, err
+}
+
+func (cr *Observer) StartAPI() *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/releases", cr.apiHandler(cr.listReleases))
+	mux.Handle("GET /api/releases/{id}", cr.apiHandler(cr.getRelease))
+	mux.HandleFunc("GET /api/releases/{id}/poster", cr.getPoster)
+	mux.Handle("POST /api/releases/{id}/announce", cr.apiHandler(cr.announceRelease))
+	mux.Handle("GET /api/releases/{id}/deliveries", cr.apiHandler(cr.listDeliveries))
+	mux.Handle("GET /api/chats", cr.apiHandler(func(_ *http.Request, db s.Database) (any, error) {
+		return idList(db.GetChats())
+	}))
+	mux.Handle("PUT /api/chats/{id}", cr.apiHandler(modifyId(s.Database.AddChat)))
+	mux.Handle("DELETE /api/chats/{id}", cr.apiHandler(modifyId(s.Database.DelChat)))
+	mux.Handle("GET /api/admins", cr.apiHandler(func(_ *http.Request, db s.Database) (any, error) {
+		return idList(db.GetAdmins())
+	}))
+	mux.Handle("PUT /api/admins/{id}", cr.apiHandler(modifyId(s.Database.AddAdmin)))
+	mux.Handle("DELETE /api/admins/{id}", cr.apiHandler(modifyId(s.Database.DelAdmin)))
+	mux.Handle("GET /api/crawler", cr.apiHandler(cr.getCrawler))
+	mux.Handle("PUT /api/crawler/offset", cr.apiHandler(cr.setOffset))
+	mux.Handle("POST /api/crawler/pause", cr.apiHandler(func(*http.Request, s.Database) (any, error) {
+		cr.Pause()
+		return nil, nil
+	}))
+	mux.Handle("POST /api/crawler/resume", cr.apiHandler(func(*http.Request, s.Database) (any, error) {
+		cr.Resume()
+		return nil, nil
+	}))
+	mux.Handle("GET /api/deadletter", cr.apiHandler(cr.listDead))
+	mux.Handle("POST /api/deadletter/requeue", cr.apiHandler(func(*http.Request, s.Database) (any, error) {
+		return nil, cr.Requeue(0)
+	}))
+	mux.Handle("POST /api/deadletter/{id}/requeue", cr.apiHandler(modifyId(func(_ s.Database, id int64) error {
+		return cr.Requeue(id)
+	})))
+	srv := &http.Server{
+		Addr:              cr.API.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		logger.Info("Starting API server on ", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(err)
+		}
+	}()
+	return srv
+}
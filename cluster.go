@@ -69,6 +69,7 @@ func (cl *Cluster) Start() error {
 	if len(cl.NatsURL) == 0 || len(cl.MasterSubject) == 0 || len(cl.ProposeSubject) == 0 || cl.StartFn == nil || cl.SuspendFn == nil {
 		return shared.ErrRequiredParameters
 	}
+	shared.AddDSNSecret(cl.NatsURL)
 	if cl.MasterPingInterval <= 0 {
 		logger.Warning("MasterPingInterval not set, using ", DefaultPingInterval)
 		cl.MasterPingInterval = DefaultPingInterval
@@ -126,6 +127,8 @@ func (cl *Cluster) asMaster() error {
 			logger.Notice("Become a master, my id: ", NodeId)
 			if cl.masterSub, err = cl.client.Subscribe(cl.MasterSubject, respondId); err == nil {
 				defer cl.unsubMaster()
+				shared.Metrics.Set(shared.MetricClusterMaster, 1)
+				defer shared.Metrics.Set(shared.MetricClusterMaster, 0)
 				err = cl.StartFn()
 			}
 		}
@@ -0,0 +1,67 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	tto "sot-te.ch/TTObserverV1"
+	s "sot-te.ch/TTObserverV1/shared"
+)
+
+// deadLetter prints dead-letter jobs or requeues them, if requeue is not negative
+func deadLetter(tt *tto.Observer, requeue int64) {
+	db, err := s.Connect(tt.DB.Driver, tt.DB.Parameters)
+	if err != nil {
+		logger.Fatal("!Unable to connect to database", err)
+	}
+	defer db.Close()
+	if requeue >= 0 {
+		if err = tto.Requeue(db, requeue); err != nil {
+			println(err.Error())
+			os.Exit(1)
+		}
+		println("Requeued")
+		return
+	}
+	var jobs []s.OutboxJob
+	for offset := uint(0); ; offset += uint(len(jobs)) {
+		if jobs, err = db.GetDeadOutboxJobs(offset, 100); err != nil {
+			println(err.Error())
+			os.Exit(1)
+		}
+		if len(jobs) == 0 {
+			break
+		}
+		for _, j := range jobs {
+			fmt.Printf("%d\t%d\t%s\t%s\t%d\t%s\n",
+				j.Id, j.Torrent, j.Producer, j.Created.Format(time.DateTime), j.Attempts, j.Error)
+		}
+	}
+}
@@ -102,6 +102,18 @@ func migrate(tt *tto.Observer, from, to string) {
 					logger.Fatal("! Unable to get torrent ", t.Id, " meta", err)
 				} else if err = newDb.AddTorrentMeta(t.Id, meta); err != nil {
 					logger.Fatal("! Unable to migrate torrent ", t.Id, " meta", err)
+				} else if images, err := oldDb.GetTorrentImages(t.Id); err != nil {
+					logger.Fatal("! Unable to get torrent ", t.Id, " images", err)
+				} else if err = newDb.AddTorrentImages(t.Id, images); err != nil {
+					logger.Fatal("! Unable to migrate torrent ", t.Id, " images", err)
+				} else if revisions, err := oldDb.GetTorrentRevisions(t.Id); err != nil {
+					logger.Fatal("! Unable to get torrent ", t.Id, " revisions", err)
+				} else {
+					for _, rev := range revisions {
+						if err = newDb.AddTorrentRevision(t.Id, rev); err != nil {
+							logger.Fatal("! Unable to migrate torrent ", t.Id, " revisions", err)
+						}
+					}
 				}
 				logger.Info(". Torrent ", t.Id, " migrated")
 			}
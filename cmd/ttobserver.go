@@ -36,6 +36,7 @@ import (
 	"github.com/op/go-logging"
 
 	tto "sot-te.ch/TTObserverV1"
+	s "sot-te.ch/TTObserverV1/shared"
 )
 
 var logger = logging.MustGetLogger("main")
@@ -45,6 +46,9 @@ func main() {
 	m := flag.Bool("m", false, "Migrate from one DB driver to another. Both DB properties must be provided")
 	f := flag.String("f", "", "Driver name from what database extract data. Supported values: sqlite3, redis, postgres")
 	t := flag.String("t", "", "Driver name to what database import data. Supported values: sqlite3, redis, postgres")
+	check := flag.Bool("check", false, "Check configuration, templates and connectivity to database and producers, then exit")
+	dead := flag.Bool("dead", false, "Print dead-letter jobs (ID, release, producer, created, attempts, error), then exit")
+	requeue := flag.Int64("requeue", -1, "Requeue dead-letter job with ID (0 - all jobs), then exit")
 	flag.Parse()
 	tt, err := tto.ReadConfig(*configPath)
 	if err != nil {
@@ -59,7 +63,7 @@ func main() {
 	if err == nil {
 		backend := logging.AddModuleLevel(
 			logging.NewBackendFormatter(
-				logging.NewLogBackend(outputWriter, "", 0),
+				logging.NewLogBackend(s.NewMaskingWriter(outputWriter), "", 0),
 				logging.MustStringFormatter("%{time:2006-01-02 15:04:05.000}\t%{shortfile}\t%{shortfunc}\t%{level}:\t%{message}")))
 		var level logging.Level
 		if level, err = logging.LogLevel(tt.Log.Level); err != nil {
@@ -71,6 +75,36 @@ func main() {
 	} else {
 		println(err)
 	}
+	if *check {
+		if err = tt.Check(*configPath); err != nil {
+			println(s.MaskSecrets(err.Error()))
+			os.Exit(1)
+		}
+		println("Configuration OK")
+		return
+	}
+	if *dead || *requeue >= 0 {
+		deadLetter(tt, *requeue)
+		return
+	}
+	if tt.DebugPort > 0 && !*m {
+		srv := tt.StartDebug()
+		defer srv.Close()
+	}
+	if len(tt.API.Listen) > 0 && !*m {
+		if len(tt.API.Tokens) == 0 {
+			logger.Fatal("API tokens not set")
+		}
+		srv := tt.StartAPI()
+		defer srv.Close()
+	}
+	if len(tt.Web.Listen) > 0 && !*m {
+		if len(tt.Web.Users) == 0 {
+			logger.Fatal("Web UI users not set")
+		}
+		srv := tt.StartWeb()
+		defer srv.Close()
+	}
 	if *m {
 		if len(*f) > 0 && len(*t) > 0 {
 			migrate(tt, *f, *t)
@@ -79,13 +113,13 @@ func main() {
 			os.Exit(1)
 		}
 	} else if len(tt.Cluster.NatsURL) > 0 {
-		startClustered(tt)
+		startClustered(tt, *configPath)
 	} else {
-		start(tt)
+		start(tt, *configPath)
 	}
 }
 
-func start(tt *tto.Observer) {
+func start(tt *tto.Observer, configPath string) {
 	if err := tt.Init(); err == nil {
 		ch := make(chan os.Signal, 2)
 		go func() {
@@ -93,9 +127,8 @@ func start(tt *tto.Observer) {
 			ch <- syscall.SIGABRT
 		}()
 		defer tt.Close()
-		signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
-		sig := <-ch
-		if sig == syscall.SIGABRT {
+		signal.Notify(ch, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+		if sig := waitSignal(ch, tt, configPath); sig == syscall.SIGABRT {
 			os.Exit(1)
 		}
 	} else {
@@ -103,7 +136,7 @@ func start(tt *tto.Observer) {
 	}
 }
 
-func startClustered(tt *tto.Observer) {
+func startClustered(tt *tto.Observer, configPath string) {
 	tt.Cluster.StartFn = func() (err error) {
 		if err = tt.Init(); err == nil {
 			tt.Engage()
@@ -118,10 +151,28 @@ func startClustered(tt *tto.Observer) {
 		}
 		ch <- syscall.SIGABRT
 	}()
-	defer tt.Cluster.Stop()
-	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
-	sig := <-ch
-	if sig == syscall.SIGABRT {
+	defer func() {
+		tt.Cluster.Stop()
+		// node may be master, its deliveries are drained as on single node
+		tt.Close()
+	}()
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	if sig := waitSignal(ch, tt, configPath); sig == syscall.SIGABRT {
 		os.Exit(1)
 	}
 }
+
+func waitSignal(ch chan os.Signal, tt *tto.Observer, configPath string) os.Signal {
+	for {
+		sig := <-ch
+		if sig != syscall.SIGHUP {
+			return sig
+		}
+		logger.Notice("Reloading configuration from ", configPath)
+		if err := tt.Reload(configPath); err == nil {
+			logger.Notice("Configuration reloaded")
+		} else {
+			logger.Error("Configuration reload failed, keeping current: ", err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package TTObserver
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	s "sot-te.ch/TTObserverV1/shared"
+)
+
+func TestReadExampleConfig(t *testing.T) {
+	cr, err := ReadConfig(filepath.Join("conf", "example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := make([]string, 0, len(cr.Crawler.MetaActions))
+	for _, a := range cr.Crawler.MetaActions {
+		params = append(params, a.Param)
+	}
+	if !slices.Contains(params, "${arg}") {
+		t.Errorf("crawler placeholder ${arg} is expanded: %q", params)
+	}
+	producers, err := filepath.Glob(filepath.Join("conf", "example_*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range producers {
+		if _, err = s.LoadConfig(path); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -27,14 +27,15 @@
 package TTObserver
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"html"
 	"net/url"
-	"os"
-	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/op/go-logging"
@@ -54,25 +55,40 @@ import (
 	_ "sot-te.ch/TTObserverV1/shared/sqldb"
 )
 
-const delay = 5
+const (
+	delay           = 5
+	shutdownTimeout = 30
+)
 
 type Observer struct {
-	Log struct {
+	DebugPort       int           `json:"debugport"`
+	ShutdownTimeout time.Duration `json:"shutdowntimeout"`
+	Log             struct {
 		File  string `json:"file"`
 		Level string `json:"level"`
 	} `json:"log"`
 	Crawler struct {
-		BaseURL        string              `json:"baseurl"`
-		ContextURL     string              `json:"contexturl"`
-		Limit          uint64              `json:"limit"`
-		Delay          time.Duration       `json:"delay"`
-		Threshold      uint                `json:"threshold"`
-		Anniversary    uint                `json:"anniversary"`
-		MetaActions    []hte.ExtractAction `json:"metaactions"`
-		MetaRetry      uint                `json:"metaretry"`
-		ImageMetaField string              `json:"imagemetafield"`
-		ImageThumb     uint                `json:"imagethumb"`
+		BaseURL        string                     `json:"baseurl"`
+		ContextURL     string                     `json:"contexturl"`
+		Limit          uint64                     `json:"limit"`
+		Delay          time.Duration              `json:"delay"`
+		Threshold      uint                       `json:"threshold"`
+		Anniversary    uint                       `json:"anniversary"`
+		MetaActions    []hte.ExtractAction        `json:"metaactions"`
+		MetaTransforms map[string][]MetaTransform `json:"metatransforms"`
+		MetaRetry      uint                       `json:"metaretry"`
+		ImageMetaField string                     `json:"imagemetafield"`
+		ImageFields    []string                   `json:"imagemetafields"`
+		ImageLimit     int                        `json:"imagelimit"`
+		ImageThumb     uint                       `json:"imagethumb"`
+		RequiredMeta   []string                   `json:"requiredmeta"`
+		HoldTime       time.Duration              `json:"holdtime"`
+		HoldRetry      time.Duration              `json:"holdretry"`
+		HoldFallback   map[string]string          `json:"holdfallback"`
+		HoldEscalate   bool                       `json:"holdescalate"`
+		Debounce       time.Duration              `json:"debounce"`
 		metaExtractor  *hte.Extractor
+		metaTransforms metaTransformer
 		baseURL        *url.URL
 	} `json:"crawler"`
 	Producers []producer.Config `json:"producers"`
@@ -80,84 +96,181 @@ type Observer struct {
 		Driver     string         `json:"driver"`
 		Parameters map[string]any `json:"params"`
 	} `json:"db"`
+	API struct {
+		Listen string   `json:"listen"`
+		Tokens []string `json:"tokens"`
+	} `json:"api"`
+	Web struct {
+		Listen string            `json:"listen"`
+		Users  map[string]string `json:"users"`
+	} `json:"web"`
 	Cluster  Cluster `json:"cluster"`
 	db       s.Database
 	producer *producer.Announcer
 	stopped  chan any
+	wake     chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	paused   atomic.Bool
+	workers  sync.WaitGroup
+	mu       sync.RWMutex
 }
 
 var (
-	logger           = logging.MustGetLogger("observer")
-	errActionsNotSet = errors.New("extract actions not set")
+	logger            = logging.MustGetLogger("observer")
+	errActionsNotSet  = errors.New("extract actions not set")
+	errNotInitialized = errors.New("observer not initialized")
 )
 
 func ReadConfig(path string) (*Observer, error) {
 	var config Observer
-	confData, err := os.ReadFile(filepath.Clean(path))
-	if err == nil {
-		err = json.Unmarshal(confData, &config)
-	}
+	err := s.ReadConfig(path, &config)
 	return &config, err
 }
 
 func (cr *Observer) Init() error {
 	var err error
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	for _, t := range cr.API.Tokens {
+		s.AddSecret(t)
+	}
+	for _, p := range cr.Web.Users {
+		s.AddSecret(p)
+	}
 	if cr.db, err = s.Connect(cr.DB.Driver, cr.DB.Parameters); err != nil {
 		return err
 	}
-	if cr.Crawler.baseURL, err = url.Parse(cr.Crawler.BaseURL); err != nil {
+	if err = cr.prepareCrawler(); err != nil {
 		return err
 	}
+	logger.Debug("Initiating notifiers")
+	cr.producer, err = producer.New(cr.Producers, cr.db)
+	if err == nil {
+		cr.producer.SetController(cr)
+		cr.stopped = make(chan any, 1)
+		cr.wake = make(chan struct{}, 1)
+		cr.ctx, cr.cancel = context.WithCancel(context.Background())
+	}
+	return err
+}
+
+func (cr *Observer) prepareCrawler() error {
+	var err error
+	var errs []error
+	if cr.Crawler.baseURL, err = url.Parse(cr.Crawler.BaseURL); err != nil {
+		errs = append(errs, fmt.Errorf("crawler.baseurl: %w", err))
+	}
 	logger.Debug("Initiating meta extractor")
 	if len(cr.Crawler.MetaActions) > 0 {
 		ex := hte.New()
-		if err := ex.Compile(cr.Crawler.MetaActions); err == nil {
+		if err = ex.Compile(cr.Crawler.MetaActions); err == nil {
 			ex.IterationLimit, ex.StackLimit = cr.Crawler.Limit, cr.Crawler.Limit
 			cr.Crawler.metaExtractor = ex
 		} else {
-			return err
+			errs = append(errs, fmt.Errorf("crawler.metaactions: %w", err))
 		}
 	} else {
-		return errActionsNotSet
+		errs = append(errs, fmt.Errorf("crawler.metaactions: %w", errActionsNotSet))
 	}
-	logger.Debug("Initiating notifiers")
-	cr.producer, err = producer.New(cr.Producers, cr.db)
+	if cr.Crawler.metaTransforms, err = compileTransforms(cr.Crawler.MetaTransforms); err != nil {
+		errs = append(errs, fmt.Errorf("crawler.metatransforms: %w", err))
+	}
+	if cr.Crawler.Delay == 0 {
+		logger.Info("Delay time set to 0, falling back to ", delay)
+		cr.Crawler.Delay = delay
+	}
+	if cr.Crawler.HoldRetry == 0 {
+		cr.Crawler.HoldRetry = cr.Crawler.Delay
+	}
+	return errors.Join(errs...)
+}
+
+// Check validates configuration read from path: compiles crawler actions and templates,
+// connects to database and checks every producer. All found problems are returned.
+func (cr *Observer) Check(path string) error {
+	var errs []error
+	for _, err := range splitErrors(cr.prepareCrawler()) {
+		errs = append(errs, s.FieldError(path, "", err))
+	}
+	db, err := s.Connect(cr.DB.Driver, cr.DB.Parameters)
 	if err == nil {
-		if cr.Crawler.Delay == 0 {
-			logger.Info("Delay time set to 0, falling back to ", delay)
-			cr.Crawler.Delay = delay
+		defer db.Close()
+		err = db.Ping()
+	}
+	if err != nil {
+		errs = append(errs, s.FieldError(path, "db", err))
+	}
+	for i, conf := range cr.Producers {
+		for _, err := range splitErrors(producer.Check(conf, db)) {
+			errs = append(errs, s.FieldError(path, fmt.Sprintf("producers[%d] (%s)", i, conf.Id), err))
 		}
-		cr.stopped = make(chan any, 1)
 	}
-	return err
+	if len(cr.Producers) == 0 {
+		errs = append(errs, s.FieldError(path, "producers", s.ErrRequiredParameters))
+	}
+	return errors.Join(errs...)
+}
+
+// Reload re-reads crawler and producers sections from config file.
+// Other sections (db, api, web, cluster, log) require restart.
+// On any error current configuration is kept.
+func (cr *Observer) Reload(path string) error {
+	conf, err := ReadConfig(path)
+	if err == nil {
+		err = conf.prepareCrawler()
+	}
+	if err != nil {
+		return err
+	}
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.producer != nil {
+		if err = cr.producer.Reload(conf.Producers); err != nil {
+			return err
+		}
+	}
+	cr.Crawler, cr.Producers = conf.Crawler, conf.Producers
+	return nil
+}
+
+func splitErrors(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, splitErrors(e)...)
+		}
+		return errs
+	} else if err != nil {
+		return []error{err}
+	}
+	return nil
 }
 
 func (cr *Observer) Engage() {
 	var err error
-	var nextOffset uint
-	t := time.NewTicker(cr.Crawler.Delay * time.Second)
+	interval := cr.Crawler.Delay * time.Second
+	t := time.NewTicker(interval)
 	defer t.Stop()
+	cr.workers.Add(2)
+	go func() {
+		defer cr.workers.Done()
+		cr.dispatch()
+	}()
+	go func() {
+		defer cr.workers.Done()
+		cr.retryHeld()
+	}()
 	for err == nil {
 		select {
 		case <-t.C:
-			nextOffset, err = cr.db.GetCrawlOffset()
-			if err != nil {
-				logger.Error(err)
-				break
-			}
-			logger.Debug("Checking upstream with offset ", nextOffset)
-			newNextOffset := nextOffset
-			for offsetToCheck := nextOffset; offsetToCheck < nextOffset+cr.Crawler.Threshold; offsetToCheck++ {
-				if cr.CheckTorrent(offsetToCheck) {
-					newNextOffset = offsetToCheck + 1
-				}
-			}
-			if newNextOffset > nextOffset {
-				nextOffset = newNextOffset
-				if err := cr.db.UpdateCrawlOffset(nextOffset); err != nil {
-					logger.Error(err)
-				}
+			cr.mu.RLock()
+			err = cr.crawl()
+			if d := cr.Crawler.Delay * time.Second; d != interval {
+				interval = d
+				t.Reset(interval)
 			}
+			cr.mu.RUnlock()
 		case <-cr.stopped:
 			return
 		}
@@ -165,16 +278,149 @@ func (cr *Observer) Engage() {
 	logger.Fatal("Offset fetch error", err)
 }
 
+func (cr *Observer) crawl() error {
+	if cr.db == nil {
+		// observer closed
+		return nil
+	}
+	if cr.paused.Load() {
+		logger.Debug("Crawler paused")
+		return nil
+	}
+	nextOffset, err := cr.db.GetCrawlOffset()
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	logger.Debug("Checking upstream with offset ", nextOffset)
+	s.Metrics.Set(s.MetricOffset, float64(nextOffset))
+	newNextOffset := nextOffset
+	for offsetToCheck := nextOffset; offsetToCheck < nextOffset+cr.Crawler.Threshold; offsetToCheck++ {
+		if cr.CheckTorrent(offsetToCheck) {
+			newNextOffset = offsetToCheck + 1
+		}
+	}
+	if newNextOffset > nextOffset {
+		if err := cr.db.UpdateCrawlOffset(newNextOffset); err != nil {
+			logger.Error(err)
+		}
+	}
+	return nil
+}
+
 func (cr *Observer) Close() {
+	// dispatcher stops taking new jobs, in-flight deliveries are drained until shutdown timeout,
+	// only then the rest is cancelled. Lock is not held while waiting, as dispatcher reads state under it
 	if cr.stopped != nil {
-		close(cr.stopped)
+		select {
+		case <-cr.stopped:
+			// already closed, i.e. on suspend of cluster node
+		default:
+			close(cr.stopped)
+		}
 	}
+	if _, announcer, err := cr.state(); err == nil {
+		timeout := cr.ShutdownTimeout
+		if timeout == 0 {
+			timeout = shutdownTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+		if err = announcer.Shutdown(ctx); err != nil {
+			logger.Warning("Pending deliveries not finished in time: ", err)
+		}
+		cancel()
+	}
+	if cr.cancel != nil {
+		cr.cancel()
+	}
+	cr.workers.Wait()
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
 	if cr.producer != nil {
 		cr.producer.Close()
 	}
 	if cr.db != nil {
 		cr.db.Close()
 	}
+	cr.producer, cr.db = nil, nil
+}
+
+func (cr *Observer) Pause() {
+	logger.Notice("Pausing crawler")
+	cr.paused.Store(true)
+}
+
+func (cr *Observer) Resume() {
+	logger.Notice("Resuming crawler")
+	cr.paused.Store(false)
+}
+
+func (cr *Observer) Paused() bool {
+	return cr.paused.Load()
+}
+
+// SetOffset sets crawl offset, waiting for current crawl to finish,
+// so it does not overwrite new offset with its own
+func (cr *Observer) SetOffset(offset uint) error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.db == nil {
+		return errNotInitialized
+	}
+	logger.Notice("Setting crawl offset to ", offset)
+	return cr.db.UpdateCrawlOffset(offset)
+}
+
+func (cr *Observer) state() (s.Database, *producer.Announcer, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	if cr.db == nil || cr.producer == nil {
+		return nil, nil, errNotInitialized
+	}
+	return cr.db, cr.producer, nil
+}
+
+func (cr *Observer) LoadTorrent(id int64) (*s.TorrentInfo, error) {
+	db, _, err := cr.state()
+	if err != nil {
+		return nil, err
+	}
+	var t s.DBTorrent
+	if t, err = db.GetTorrentById(id); err != nil {
+		return nil, err
+	}
+	var torrent *s.TorrentInfo
+	if torrent, err = s.ParseTorrent(t.Data); err == nil {
+		for f := range torrent.Files {
+			torrent.Files[f] = false
+		}
+		torrent.Id, torrent.Image = t.Id, t.Image
+		if len(t.URL) > 0 {
+			torrent.URL = t.URL
+		}
+		if torrent.Meta, err = db.GetTorrentMeta(id); err == nil {
+			torrent.Images, err = db.GetTorrentImages(id)
+		}
+		if err == nil {
+			var revisions []time.Time
+			if revisions, err = db.GetTorrentRevisions(id); err == nil && len(revisions) > 0 {
+				torrent.Revision = revisions[len(revisions)-1]
+			}
+		}
+	}
+	return torrent, err
+}
+
+func (cr *Observer) Reannounce(id int64, producers []string) error {
+	db, announcer, err := cr.state()
+	if err == nil {
+		var torrent *s.TorrentInfo
+		if torrent, err = cr.LoadTorrent(id); err == nil {
+			logger.Notice("Reannouncing release ", id, " to ", producers)
+			err = cr.enqueue(db, announcer, torrent, false, producers, 0)
+		}
+	}
+	return err
 }
 
 func (cr *Observer) CheckTorrent(offset uint) bool {
@@ -207,29 +453,183 @@ func (cr *Observer) CheckTorrent(offset uint) bool {
 					logger.Error(err)
 				}
 				isNew = torrentId == s.InvalidDBId
-				if torrentId, err = cr.db.AddTorrent(torrent.Name, torrent.Data, torrent.NewFiles()); err != nil {
+				torrent.Revision = time.Now()
+				if torrentId, err = cr.db.AddTorrent(torrent.Name, fullURL, torrent.Data, torrent.NewFiles()); err == nil {
+					err = cr.db.AddTorrentRevision(torrentId, torrent.Revision)
+				}
+				if err != nil {
 					logger.Error(err)
 				}
 				torrent.Id, torrent.URL = torrentId, fullURL
 				cr.notify(torrent, fullContext, isNew)
 				if offset > 0 && offset%cr.Crawler.Anniversary == 0 {
-					cr.producer.SendNxGet(offset)
+					cr.producer.SendNxGet(cr.ctx, offset)
 				}
 				res = true
+				s.Metrics.Add(s.MetricProbes, 1, "outcome", "found")
 			} else {
 				logger.Error("Zero torrent size, offset", offset)
+				s.Metrics.Add(s.MetricProbes, 1, "outcome", "empty")
 			}
+		} else {
+			s.Metrics.Add(s.MetricProbes, 1, "outcome", "missing")
 		}
 	} else {
 		logger.Error(err)
+		s.Metrics.Add(s.MetricProbes, 1, "outcome", "error")
 	}
 	return res
 }
 
 func (cr *Observer) notify(torrent *s.TorrentInfo, context string, isNew bool) {
+	cr.fetchMeta(torrent, context)
+	if missing := cr.missingMeta(torrent.Meta); len(missing) > 0 && cr.Crawler.HoldTime > 0 {
+		logger.Warning("Release ", torrent.Name, " misses required meta ", missing, ", holding")
+		cr.hold(torrent, context, isNew)
+	} else if len(missing) > 0 {
+		cr.releaseHeld(torrent, isNew, missing)
+	} else {
+		cr.announce(torrent, isNew)
+	}
+}
+
+func (cr *Observer) missingMeta(meta map[string]string) []string {
+	var missing []string
+	for _, f := range cr.Crawler.RequiredMeta {
+		if len(meta[f]) == 0 {
+			missing = append(missing, f)
+		}
+	}
+	return missing
+}
+
+func (cr *Observer) hold(torrent *s.TorrentInfo, context string, isNew bool) {
+	var err error
+	held := *torrent
+	held.Meta, held.Image = nil, nil
+	now := time.Now()
+	p := s.PendingTorrent{
+		Id:      torrent.Id,
+		Context: context,
+		IsNew:   isNew,
+		Since:   now,
+		Retry:   now.Add(cr.Crawler.HoldRetry * time.Second),
+	}
+	if p.Torrent, err = json.Marshal(held); err == nil {
+		err = cr.db.AddPendingTorrent(p)
+	}
+	if err != nil {
+		logger.Error("Unable to hold release ", torrent.Name, ": ", err, ", announcing as is")
+		cr.announce(torrent, isNew)
+	}
+}
+
+// retryHeld retries held releases every crawler.holdretry until observer stopped
+func (cr *Observer) retryHeld() {
+	interval := cr.holdRetry()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-cr.stopped:
+			return
+		}
+		cr.checkHeld()
+		if d := cr.holdRetry(); d != interval {
+			interval = d
+			t.Reset(interval)
+		}
+	}
+}
+
+func (cr *Observer) holdRetry() time.Duration {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return max(cr.Crawler.HoldRetry, 1) * time.Second
+}
+
+// checkHeld retries due held releases, lock is taken per release
+// as meta fetch may take a while
+func (cr *Observer) checkHeld() {
+	if cr.paused.Load() {
+		return
+	}
+	db, _, err := cr.state()
+	if err != nil {
+		return
+	}
+	pending, err := db.GetPendingTorrents()
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	s.Metrics.Set(s.MetricHeld, float64(len(pending)))
+	now := time.Now()
+	for _, p := range pending {
+		if now.Before(p.Retry) {
+			continue
+		}
+		select {
+		case <-cr.stopped:
+			return
+		default:
+		}
+		cr.mu.RLock()
+		cr.retryPending(p, now)
+		cr.mu.RUnlock()
+	}
+}
+
+func (cr *Observer) retryPending(p s.PendingTorrent, now time.Time) {
+	var err error
+	torrent := new(s.TorrentInfo)
+	if err = json.Unmarshal(p.Torrent, torrent); err == nil {
+		logger.Debug("Retrying held release ", torrent.Name)
+		cr.fetchMeta(torrent, p.Context)
+		if missing := cr.missingMeta(torrent.Meta); len(missing) == 0 {
+			logger.Info("Held release ", torrent.Name, " has all required meta, announcing")
+			cr.announce(torrent, p.IsNew)
+		} else if now.Sub(p.Since) >= cr.Crawler.HoldTime*time.Second {
+			cr.releaseHeld(torrent, p.IsNew, missing)
+		} else {
+			p.Retries++
+			p.Retry = now.Add(cr.Crawler.HoldRetry * time.Second)
+			if err = cr.db.AddPendingTorrent(p); err != nil {
+				logger.Error(err)
+			}
+			return
+		}
+	} else {
+		logger.Error("Unable to restore held release ", p.Id, ": ", err)
+	}
+	if err = cr.db.DelPendingTorrent(p.Id); err != nil {
+		logger.Error(err)
+	}
+}
+
+func (cr *Observer) releaseHeld(torrent *s.TorrentInfo, isNew bool, missing []string) {
+	if cr.Crawler.HoldEscalate {
+		logger.Warning("Release ", torrent.Name, " still misses required meta ", missing, ", escalating")
+		cr.producer.NotifyAdmins(cr.ctx, fmt.Sprintf("Release %d (%s) was not announced, missing meta: %s\n%s",
+			torrent.Id, torrent.Name, strings.Join(missing, ", "), torrent.URL))
+	} else {
+		logger.Warning("Release ", torrent.Name, " still misses required meta ", missing, ", announcing with fallbacks")
+		if torrent.Meta == nil {
+			torrent.Meta = make(map[string]string, len(missing))
+		}
+		for _, f := range missing {
+			torrent.Meta[f] = cr.Crawler.HoldFallback[f]
+		}
+		cr.announce(torrent, isNew)
+	}
+}
+
+func (cr *Observer) fetchMeta(torrent *s.TorrentInfo, context string) {
 	var err error
 	var upstreamMeta, existingMeta map[string]string
 	var torrentImageUrl string
+	var galleryURLs []string
 	if cr.Crawler.metaExtractor != nil {
 		var rawMeta map[string][]byte
 		logger.Debug("Extracting meta for torrent ", torrent.Name)
@@ -245,13 +645,15 @@ func (cr *Observer) notify(torrent *s.TorrentInfo, context string, isNew bool) {
 			upstreamMeta = make(map[string]string, len(rawMeta))
 			for k, v := range rawMeta {
 				if len(k) > 0 {
-					unescapedValue := strings.TrimSpace(html.UnescapeString(string(v)))
-					upstreamMeta[k] = unescapedValue
-					if k == cr.Crawler.ImageMetaField {
-						torrentImageUrl = unescapedValue
-					}
+					upstreamMeta[k] = strings.TrimSpace(html.UnescapeString(string(v)))
 				}
 			}
+			if err = cr.Crawler.metaTransforms.apply(upstreamMeta); err != nil {
+				logger.Warning(err)
+				err = nil
+			}
+			torrentImageUrl = upstreamMeta[cr.Crawler.ImageMetaField]
+			galleryURLs = cr.galleryURLs(upstreamMeta)
 		}
 	}
 	if err != nil {
@@ -275,10 +677,7 @@ func (cr *Observer) notify(torrent *s.TorrentInfo, context string, isNew bool) {
 		if len(torrentImage) == 0 || existingMeta[cr.Crawler.ImageMetaField] != torrentImageUrl {
 			if len(torrentImageUrl) > 0 {
 				logger.Info("Reloading torrent image")
-				if !strings.Contains(torrentImageUrl, cr.Crawler.BaseURL) {
-					torrentImageUrl = cr.Crawler.baseURL.JoinPath(torrentImageUrl).String()
-				}
-				if torrentImage, err = s.GetTorrentPoster(torrentImageUrl, cr.Crawler.ImageThumb); err == nil {
+				if torrentImage, err = s.GetTorrentPoster(cr.absoluteURL(torrentImageUrl), cr.Crawler.ImageThumb); err == nil {
 					err = cr.db.AddTorrentImage(torrent.Id, torrentImage)
 				}
 			}
@@ -287,6 +686,54 @@ func (cr *Observer) notify(torrent *s.TorrentInfo, context string, isNew bool) {
 	if err != nil {
 		logger.Error(err)
 	}
-	torrent.Meta, torrent.Image = upstreamMeta, torrentImage
-	cr.producer.Send(isNew, torrent)
+	var gallery [][]byte
+	if gallery, err = cr.db.GetTorrentImages(torrent.Id); err == nil {
+		if len(galleryURLs) > 0 && (len(gallery) == 0 || cr.galleryChanged(existingMeta, upstreamMeta)) {
+			logger.Info("Reloading torrent gallery")
+			gallery = make([][]byte, 0, len(galleryURLs))
+			for _, u := range galleryURLs {
+				if img, imgErr := s.GetTorrentPoster(cr.absoluteURL(u), cr.Crawler.ImageThumb); imgErr == nil {
+					gallery = append(gallery, img)
+				} else {
+					logger.Warning("Unable to load gallery image ", u, ": ", imgErr)
+				}
+			}
+			err = cr.db.AddTorrentImages(torrent.Id, gallery)
+		}
+	}
+	if err != nil {
+		logger.Error(err)
+	}
+	torrent.Meta, torrent.Image, torrent.Images = upstreamMeta, torrentImage, gallery
+}
+
+func (cr *Observer) galleryURLs(meta map[string]string) []string {
+	var urls []string
+	for _, f := range cr.Crawler.ImageFields {
+		for _, u := range strings.Split(meta[f], s.MetaValueSeparator) {
+			if u = strings.TrimSpace(u); len(u) > 0 {
+				urls = append(urls, u)
+			}
+		}
+	}
+	if cr.Crawler.ImageLimit > 0 && len(urls) > cr.Crawler.ImageLimit {
+		urls = urls[:cr.Crawler.ImageLimit]
+	}
+	return urls
+}
+
+func (cr *Observer) galleryChanged(existingMeta, upstreamMeta map[string]string) bool {
+	for _, f := range cr.Crawler.ImageFields {
+		if existingMeta[f] != upstreamMeta[f] {
+			return true
+		}
+	}
+	return false
+}
+
+func (cr *Observer) absoluteURL(u string) string {
+	if !strings.Contains(u, cr.Crawler.BaseURL) {
+		u = cr.Crawler.baseURL.JoinPath(u).String()
+	}
+	return u
 }
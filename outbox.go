@@ -0,0 +1,370 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package TTObserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sot-te.ch/TTObserverV1/producer"
+	s "sot-te.ch/TTObserverV1/shared"
+)
+
+const (
+	outboxBatch     = 100
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// announce stores release in outbox to be delivered by dispatcher,
+// if outbox is not available, release sent directly
+func (cr *Observer) announce(torrent *s.TorrentInfo, isNew bool) {
+	kind := "updated"
+	if isNew {
+		kind = "new"
+	}
+	s.Metrics.Add(s.MetricAnnounced, 1, "kind", kind)
+	var debounce time.Duration
+	if !isNew {
+		debounce = cr.Crawler.Debounce * time.Second
+	}
+	if err := cr.enqueue(cr.db, cr.producer, torrent, isNew, nil, debounce); err != nil {
+		logger.Error("Unable to store release ", torrent.Id, " in outbox: ", err, ", sending directly")
+		cr.producer.Send(cr.ctx, isNew, torrent)
+	}
+}
+
+// enqueue adds outbox job for every producer from list, or for every active producer if list is empty.
+// If debounce is set, job is postponed by debounce and merged into scheduled job of the same release
+func (cr *Observer) enqueue(db s.Database, announcer *producer.Announcer, torrent *s.TorrentInfo, isNew bool, producers []string, debounce time.Duration) error {
+	ids := announcer.Ids()
+	if len(producers) == 0 {
+		producers = ids
+	}
+	for _, id := range producers {
+		if !slices.Contains(ids, id) {
+			return fmt.Errorf("producer %s: %w", id, s.ErrNotFound)
+		}
+	}
+	if len(producers) == 0 {
+		return nil
+	}
+	// images are stored separately, no need to keep them twice
+	payload := *torrent
+	payload.Image, payload.Images = nil, nil
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if debounce > 0 {
+		if producers, err = debounceJobs(db, payload, producers, now, debounce); err != nil || len(producers) == 0 {
+			return err
+		}
+	}
+	jobs := make([]s.OutboxJob, 0, len(producers))
+	for _, id := range producers {
+		conf, _ := announcer.Config(id)
+		// digest jobs wait for the end of window
+		next := conf.Digest.Next(now)
+		if d := now.Add(debounce); d.After(next) {
+			next = d
+		}
+		jobs = append(jobs, s.OutboxJob{
+			Torrent:  torrent.Id,
+			Producer: id,
+			IsNew:    isNew,
+			Created:  now,
+			NextTry:  next,
+			Payload:  data,
+		})
+	}
+	if err = db.AddOutboxJobs(jobs); err == nil {
+		select {
+		case cr.wake <- struct{}{}:
+		default:
+		}
+	}
+	return err
+}
+
+// debounceJobs merges release into jobs of the same release, which are not due yet, and postpones them by debounce,
+// new files of merged job are kept. Returns producers without such jobs
+func debounceJobs(db s.Database, torrent s.TorrentInfo, producers []string, now time.Time, debounce time.Duration) ([]string, error) {
+	// jobs due before next dispatcher tick may be already taken
+	jobs, err := db.GetScheduledOutboxJobs(now.Add(delay * time.Second))
+	if err != nil {
+		return nil, err
+	}
+	rest := slices.Clone(producers)
+	for _, j := range jobs {
+		if j.Torrent != torrent.Id || !slices.Contains(rest, j.Producer) {
+			continue
+		}
+		var prev s.TorrentInfo
+		if err = json.Unmarshal(j.Payload, &prev); err != nil {
+			logger.Warning("Unable to read outbox job ", j.Id, " payload: ", err)
+			continue
+		}
+		merged := torrent
+		merged.Files = make(map[string]bool, len(torrent.Files))
+		for file, isNew := range torrent.Files {
+			merged.Files[file] = isNew
+		}
+		// files added by any of merged uploads stay new
+		for file, isNew := range prev.Files {
+			if isNew {
+				merged.Files[file] = true
+			}
+		}
+		if d := now.Add(debounce); d.After(j.NextTry) {
+			j.NextTry = d
+		}
+		if j.Payload, err = json.Marshal(merged); err != nil {
+			return nil, err
+		}
+		if err = db.UpdateOutboxJob(j); err != nil {
+			return nil, err
+		}
+		logger.Debug("Release ", torrent.Id, " update merged into outbox job ", j.Id)
+		rest = slices.DeleteFunc(rest, func(id string) bool { return id == j.Producer })
+	}
+	return rest, nil
+}
+
+// dispatch delivers due outbox jobs until observer stopped
+func (cr *Observer) dispatch() {
+	t := time.NewTicker(delay * time.Second)
+	defer t.Stop()
+	var purged time.Time
+	for {
+		select {
+		case <-t.C:
+		case <-cr.wake:
+		case <-cr.stopped:
+			return
+		}
+		db, announcer, err := cr.state()
+		if err != nil {
+			continue
+		}
+		cr.dispatchDue(db, announcer)
+		if time.Since(purged) >= time.Hour {
+			if err = db.PurgeOutbox(time.Now().Add(-outboxRetention)); err != nil {
+				logger.Error("Unable to purge outbox: ", err)
+			}
+			purged = time.Now()
+		}
+	}
+}
+
+// dispatchDue delivers due jobs, jobs of every producer are delivered in order of creation
+// by producer's concurrency at once, producers are served concurrently.
+// Jobs created after failed job, which waits for retry, are not returned by database until it is done or dead
+func (cr *Observer) dispatchDue(db s.Database, announcer *producer.Announcer) {
+	jobs, err := db.GetOutboxJobs(time.Now(), outboxBatch)
+	if err != nil {
+		logger.Error("Unable to get outbox jobs: ", err)
+		return
+	}
+	queues := make(map[string][]s.OutboxJob)
+	for _, j := range jobs {
+		queues[j.Producer] = append(queues[j.Producer], j)
+	}
+	var wg sync.WaitGroup
+	for id, queue := range queues {
+		conf, _ := announcer.Config(id)
+		size, deliver := int(max(conf.Concurrency, 1)), cr.deliverBatch
+		if conf.Digest.Window > 0 {
+			size, deliver = len(queue), cr.deliverDigest
+			if conf.Digest.MaxItems > 0 {
+				size = int(conf.Digest.MaxItems)
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for len(queue) > 0 {
+				batch := queue[:min(size, len(queue))]
+				queue = queue[len(batch):]
+				if !deliver(db, announcer, batch) {
+					// keep order of producer's jobs
+					break
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// deliverBatch delivers jobs concurrently, returns false if any job is not done
+func (cr *Observer) deliverBatch(db s.Database, announcer *producer.Announcer, jobs []s.OutboxJob) bool {
+	if len(jobs) == 1 {
+		return cr.deliver(db, announcer, jobs[0])
+	}
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+	for _, j := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !cr.deliver(db, announcer, j) {
+				failed.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+	return !failed.Load()
+}
+
+// deliverDigest sends jobs through producer as one digest message, returns false if jobs are not done
+func (cr *Observer) deliverDigest(db s.Database, announcer *producer.Announcer, jobs []s.OutboxJob) bool {
+	items := make([]producer.DigestItem, 0, len(jobs))
+	valid := make([]s.OutboxJob, 0, len(jobs))
+	for _, j := range jobs {
+		if torrent, err := restore(j); err == nil {
+			items = append(items, producer.DigestItem{IsNew: j.IsNew, Torrent: torrent})
+			valid = append(valid, j)
+		} else {
+			cr.complete(db, announcer, j, err)
+		}
+	}
+	if len(items) == 0 {
+		return true
+	}
+	_, err := announcer.DeliverDigest(cr.ctx, valid[0].Producer, items)
+	done := true
+	for _, j := range valid {
+		done = cr.complete(db, announcer, j, err) && done
+	}
+	return done
+}
+
+// deliver sends job through producer and stores result, returns false if job is not done
+func (cr *Observer) deliver(db s.Database, announcer *producer.Announcer, j s.OutboxJob) bool {
+	torrent, err := restore(j)
+	if err == nil {
+		if torrent.Image, err = db.GetTorrentImage(j.Torrent); err == nil {
+			torrent.Images, err = db.GetTorrentImages(j.Torrent)
+		}
+		if err == nil {
+			_, err = announcer.Deliver(cr.ctx, j.Producer, j.IsNew, torrent)
+		}
+	}
+	return cr.complete(db, announcer, j, err)
+}
+
+// restore returns release stored in job without images
+func restore(j s.OutboxJob) (*s.TorrentInfo, error) {
+	torrent := new(s.TorrentInfo)
+	if err := json.Unmarshal(j.Payload, torrent); err != nil {
+		// broken payload will never be delivered
+		return nil, fmt.Errorf("%w: %v", s.ErrNotFound, err)
+	}
+	return torrent, nil
+}
+
+// complete stores result of job delivery, returns false if job is not done
+func (cr *Observer) complete(db s.Database, announcer *producer.Announcer, j s.OutboxJob, err error) bool {
+	if errors.Is(err, producer.ErrClosed) || cr.ctx.Err() != nil {
+		// observer is stopping, job will be retried after restart
+		return false
+	}
+	now := time.Now()
+	var de *producer.DeliveryError
+	isDelivery := errors.As(err, &de)
+	if errors.Is(err, producer.ErrCircuitOpen) {
+		// producer is paused by circuit breaker, attempt is not counted,
+		// but error is kept to hold back next jobs of producer
+		j.NextTry, j.Error = now.Add(de.RetryAfter), err.Error()
+	} else {
+		j.Attempts++
+		switch {
+		case err == nil:
+			j.Done, j.Error = now, ""
+		case errors.Is(err, s.ErrNotFound):
+			logger.Warning("Outbox job ", j.Id, " dropped: ", err)
+			j.Done, j.Error = now, err.Error()
+		default:
+			conf, _ := announcer.Config(j.Producer)
+			policy := conf.Retry
+			j.Error = err.Error()
+			if policy.Exhausted(j.Attempts) || isDelivery && !producer.IsTemporary(err) {
+				logger.Error("Outbox job ", j.Id, " moved to dead-letter after ", j.Attempts, " attempts: ", err)
+				j.Dead = true
+			} else {
+				next := policy.Delay(j.Attempts)
+				if isDelivery && de.RetryAfter > next {
+					next = de.RetryAfter
+				}
+				j.NextTry = now.Add(next)
+			}
+		}
+	}
+	if err := db.UpdateOutboxJob(j); err != nil {
+		logger.Error("Unable to update outbox job ", j.Id, ": ", err)
+	}
+	// dead job does not block next ones
+	return !j.Done.IsZero() || j.Dead
+}
+
+// Requeue moves dead-letter job back to outbox, all dead jobs are requeued if id is 0
+func (cr *Observer) Requeue(id int64) error {
+	db, _, err := cr.state()
+	if err != nil {
+		return err
+	}
+	if err = Requeue(db, id); err == nil {
+		select {
+		case cr.wake <- struct{}{}:
+		default:
+		}
+	}
+	return err
+}
+
+// Requeue moves dead-letter job back to outbox of db, all dead jobs are requeued if id is 0
+func Requeue(db s.Database, id int64) error {
+	if id != 0 {
+		return db.RequeueOutboxJob(id, time.Now())
+	}
+	for {
+		jobs, err := db.GetDeadOutboxJobs(0, outboxBatch)
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+		for _, j := range jobs {
+			if err = db.RequeueOutboxJob(j.Id, time.Now()); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package producer
+
+import (
+	"context"
+
+	tts "sot-te.ch/TTObserverV1/shared"
+)
+
+type adapter struct {
+	Producer
+}
+
+// ErrorReporter may be implemented by first version producer to return send error,
+// adapter calls TrySend instead of Send, so error is counted and delivery is retried
+type ErrorReporter interface {
+	TrySend(isNew bool, torrent *tts.TorrentInfo) error
+}
+
+// Adapt wraps first version producer to ProducerV2.
+// Wrapped producer does not report receipts, errors are reported only by ErrorReporter.
+func Adapt(p Producer) ProducerV2 {
+	return adapter{Producer: p}
+}
+
+func (a adapter) Send(ctx context.Context, isNew bool, torrent *tts.TorrentInfo) ([]Receipt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if r, ok := a.Producer.(ErrorReporter); ok {
+		return nil, r.TrySend(isNew, torrent)
+	}
+	a.Producer.Send(isNew, torrent)
+	return nil, nil
+}
+
+func (a adapter) SendNxGet(ctx context.Context, offset uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	a.Producer.SendNxGet(offset)
+	return nil
+}
+
+func (adapter) Capabilities() Capability {
+	return 0
+}
+
+func (a adapter) Close() error {
+	a.Producer.Close()
+	return nil
+}
+
+// optional returns producer's implementation of optional interface T,
+// looking through adapter
+func optional[T any](p ProducerV2) (T, bool) {
+	if a, ok := p.(adapter); ok {
+		t, ok := a.Producer.(T)
+		return t, ok
+	}
+	t, ok := p.(T)
+	return t, ok
+}
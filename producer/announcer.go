@@ -27,49 +27,78 @@
 package producer
 
 import (
+	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	tts "sot-te.ch/TTObserverV1/shared"
 )
 
+type namedProducer struct {
+	ProducerV2
+	id       string
+	conf     Config
+	digest   [sha256.Size]byte
+	inflight *sync.WaitGroup
+	breaker  *breaker
+	queue    queue
+}
+
+func newNamed(conf Config, producer ProducerV2, digest [sha256.Size]byte) namedProducer {
+	return namedProducer{
+		ProducerV2: producer,
+		id:         conf.Id,
+		conf:       conf,
+		digest:     digest,
+		inflight:   new(sync.WaitGroup),
+		breaker:    new(breaker),
+		queue:      newQueue(conf.Concurrency),
+	}
+}
+
 type Announcer struct {
-	producers []Producer
-	db        tts.Database
+	producers  []namedProducer
+	db         tts.Database
+	deliveries *deliveryLog
+	mu         sync.RWMutex
+	reloading  sync.Mutex
+	ctx        context.Context
+	cancel     context.CancelFunc
+	closed     bool
+	controller Controller
 }
 
-var producers = make(map[string]Producer)
+var ErrClosed = errors.New("announcer closed")
+
+// closeTimeout bounds waiting for in-flight sends on Close, after that they are cancelled
+const closeTimeout = 30 * time.Second
+
+var producers = make(map[string]ProducerV2)
 
 func New(configs []Config, db tts.Database) (*Announcer, error) {
 	var err error
 	a := &Announcer{
-		producers: make([]Producer, 0),
-		db:        db,
+		producers:  make([]namedProducer, 0),
+		db:         db,
+		deliveries: newDeliveryLog(),
 	}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
 	if len(configs) > 0 {
 		for i, conf := range configs {
-			if fac := factories[conf.Type]; fac != nil {
-				var producer Producer
-				var exist bool
-				if len(conf.Id) == 0 {
-					logger.Warning("id not set, using '", conf.Type, "', it may make collisions")
-					conf.Id = conf.Type
-				}
-				if producer, exist = producers[conf.Id]; exist && producer != nil {
-					logger.Notice("Using already initiated producer ", conf.Id)
-				} else {
-					logger.Debug("Initiating new producer ", conf.Type)
-					if producer, err = fac.New(conf.ConfigPath, db); err == nil {
-						if producer != nil {
-							a.producers = append(a.producers, producer)
-							producers[conf.Id] = producer
-						} else {
-							err = errors.New(fmt.Sprint("unable to construct producer #", i, " type: ", conf.Type))
-						}
-					}
-				}
+			conf = conf.withId()
+			var producer ProducerV2
+			var exist bool
+			if producer, exist = producers[conf.Id]; exist && producer != nil {
+				logger.Notice("Using already initiated producer ", conf.Id)
 			} else {
-				err = errors.New(fmt.Sprint("producer #", i, " unknown type: ", conf.Type))
+				var digest [sha256.Size]byte
+				if producer, digest, err = newProducer(i, conf, db); err == nil {
+					a.producers = append(a.producers, newNamed(conf, producer, digest))
+					producers[conf.Id] = producer
+				}
 			}
 			if err != nil {
 				logger.Error(err)
@@ -82,22 +111,551 @@ func New(configs []Config, db tts.Database) (*Announcer, error) {
 	return a, err
 }
 
-func (a *Announcer) Send(isNew bool, torrent *tts.TorrentInfo) {
-	if torrent != nil {
-		for _, n := range a.producers {
-			go n.Send(isNew, torrent)
+func (conf Config) withId() Config {
+	if len(conf.Id) == 0 {
+		logger.Warning("id not set, using '", conf.Type, "', it may make collisions")
+		conf.Id = conf.Type
+	}
+	return conf
+}
+
+// digest returns checksum of producer config, which is used to detect changes on reload
+func (conf Config) digest() (data []byte, sum [sha256.Size]byte, err error) {
+	if data, err = conf.load(); err == nil {
+		sum = sha256.Sum256(append([]byte(conf.Type), data...))
+	}
+	return
+}
+
+func newProducer(i int, conf Config, db tts.Database) (producer ProducerV2, digest [sha256.Size]byte, err error) {
+	if fac := factories[conf.Type]; fac != nil {
+		var data []byte
+		if data, digest, err = conf.digest(); err == nil {
+			logger.Debug("Initiating new producer ", conf.Type)
+			if producer, err = construct(fac, conf, data, db); err == nil && producer == nil {
+				err = errors.New(fmt.Sprint("unable to construct producer #", i, " type: ", conf.Type))
+			}
+		}
+		err = conf.locate(err)
+		if err == nil && conf.Digest.Window > 0 {
+			if _, err = conf.Digest.compile(); err == nil && !producer.Capabilities().Has(CapBatch) {
+				err = errBatchNotSupported
+			}
+			if err != nil {
+				_ = producer.Close()
+				err = fmt.Errorf("producer #%d: %w", i, err)
+			}
+		}
+	} else {
+		err = errors.New(fmt.Sprint("producer #", i, " unknown type: ", conf.Type))
+	}
+	return
+}
+
+// Reload restarts producers whose config entry or config file changed,
+// starts new ones and closes removed ones. Unchanged producers are kept.
+// If any producer fails to start, previous set of producers is restored.
+func (a *Announcer) Reload(configs []Config) error {
+	a.reloading.Lock()
+	defer a.reloading.Unlock()
+	a.mu.Lock()
+	previous := a.producers
+	current := make(map[string]namedProducer, len(previous))
+	for _, n := range previous {
+		current[n.id] = n
+	}
+	type pending struct {
+		i    int
+		conf Config
+	}
+	var next []namedProducer
+	var starting []pending
+	for i, conf := range configs {
+		conf = conf.withId()
+		n, exist := current[conf.Id]
+		if exist && n.conf.Type == conf.Type && n.conf.Concurrency == conf.Concurrency {
+			if _, digest, err := conf.digest(); err == nil && n.digest == digest {
+				// retry and breaker policies are applied without restart
+				n.conf = conf
+				next = append(next, n)
+				delete(current, conf.Id)
+				continue
+			}
+		}
+		next = append(next, namedProducer{id: conf.Id, conf: conf})
+		starting = append(starting, pending{i: i, conf: conf})
+	}
+	// stopping changed producers before starting new instances,
+	// as they may hold exclusive resources (sessions, files).
+	// They are detached first, so no new sends are queued while in-flight ones are drained without lock
+	kept := make([]namedProducer, 0, len(previous))
+	for _, n := range previous {
+		if _, stopped := current[n.id]; !stopped {
+			kept = append(kept, n)
+		}
+	}
+	a.producers = kept
+	a.mu.Unlock()
+	for _, n := range current {
+		logger.Notice("Stopping producer ", n.id)
+		n.inflight.Wait()
+		n.close()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id := range current {
+		delete(producers, id)
+	}
+	if a.closed {
+		return ErrClosed
+	}
+	var err error
+	started := make(map[string]namedProducer, len(starting))
+	for _, p := range starting {
+		var producer ProducerV2
+		var digest [sha256.Size]byte
+		if producer, digest, err = newProducer(p.i, p.conf, a.db); err != nil {
+			break
+		}
+		started[p.conf.Id] = newNamed(p.conf, producer, digest)
+		a.control(producer)
+	}
+	if err != nil {
+		logger.Error("Producers reload failed, restoring previous: ", err)
+		for _, n := range started {
+			n.close()
+		}
+		restored := make([]namedProducer, 0, len(previous))
+		for i, n := range previous {
+			if _, stopped := current[n.id]; stopped {
+				if producer, _, restoreErr := newProducer(i, n.conf, a.db); restoreErr == nil {
+					n = newNamed(n.conf, producer, n.digest)
+					a.control(producer)
+				} else {
+					logger.Error("Unable to restore producer ", n.id, ": ", restoreErr)
+					continue
+				}
+			}
+			restored = append(restored, n)
+			producers[n.id] = n.ProducerV2
+		}
+		a.producers = restored
+		return err
+	}
+	for i := range next {
+		if n, exist := started[next[i].id]; exist {
+			logger.Notice("Started producer ", n.id)
+			next[i] = n
+			producers[n.id] = n.ProducerV2
 		}
 	}
+	a.producers = next
+	return nil
 }
 
-func (a *Announcer) SendNxGet(offset uint) {
+// SetController passes observer controller to producers implementing Controlled
+func (a *Announcer) SetController(c Controller) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.controller = c
 	for _, n := range a.producers {
-		go n.SendNxGet(offset)
+		a.control(n.ProducerV2)
 	}
 }
 
-func (a *Announcer) Close() {
+func (a *Announcer) control(producer ProducerV2) {
+	if c, ok := producer.(Controlled); ok && a.controller != nil {
+		c.SetController(a.controller)
+	}
+}
+
+func (a *Announcer) list() []namedProducer {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.producers
+}
+
+// Send announces release through every producer in background
+func (a *Announcer) Send(ctx context.Context, isNew bool, torrent *tts.TorrentInfo) {
+	if torrent == nil {
+		return
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		logger.Error("Release ", torrent.Id, " not announced: ", ErrClosed)
+		return
+	}
+	for _, n := range a.producers {
+		a.deliveries.set(torrent.Id, Delivery{Producer: n.id, Status: DeliverySending})
+		var receipts []Receipt
+		a.spawn(ctx, n, func(ctx context.Context) (err error) {
+			receipts, err = a.send(ctx, n, isNew, torrent)
+			return
+		}, func(err error) {
+			a.delivered(n, torrent, receipts, err)
+		})
+	}
+}
+
+// Deliver announces release through producer with provided id and waits for result
+func (a *Announcer) Deliver(ctx context.Context, id string, isNew bool, torrent *tts.TorrentInfo) ([]Receipt, error) {
+	return a.deliver(ctx, id, []*tts.TorrentInfo{torrent}, func(ctx context.Context, n namedProducer) ([]Receipt, error) {
+		return a.send(ctx, n, isNew, torrent)
+	})
+}
+
+// DeliverDigest sends digest of items through producer with provided id and waits for result
+func (a *Announcer) DeliverDigest(ctx context.Context, id string, items []DigestItem) ([]Receipt, error) {
+	torrents := make([]*tts.TorrentInfo, 0, len(items))
+	for _, item := range items {
+		torrents = append(torrents, item.Torrent)
+	}
+	return a.deliver(ctx, id, torrents, func(ctx context.Context, n namedProducer) ([]Receipt, error) {
+		bs, ok := optional[BatchSender](n.ProducerV2)
+		if !ok {
+			return nil, &DeliveryError{Err: errBatchNotSupported}
+		}
+		tmpl, err := n.conf.Digest.compile()
+		var msg string
+		if err == nil {
+			msg, err = formatDigest(tmpl, items)
+		}
+		if err != nil {
+			return nil, &DeliveryError{Err: err}
+		}
+		return bs.SendBatch(ctx, msg)
+	})
+}
+
+// deliver runs send in queue of producer with provided id and waits for result,
+// delivery of every torrent is recorded with the same result
+func (a *Announcer) deliver(ctx context.Context, id string, torrents []*tts.TorrentInfo,
+	send func(context.Context, namedProducer) ([]Receipt, error)) ([]Receipt, error) {
+	a.mu.RLock()
+	if a.closed {
+		a.mu.RUnlock()
+		return nil, ErrClosed
+	}
+	var n namedProducer
+	var found bool
+	for _, n = range a.producers {
+		if found = n.id == id; found {
+			break
+		}
+	}
+	if !found {
+		a.mu.RUnlock()
+		return nil, fmt.Errorf("producer %s: %w", id, tts.ErrNotFound)
+	}
+	for _, torrent := range torrents {
+		a.deliveries.set(torrent.Id, Delivery{Producer: n.id, Status: DeliverySending})
+	}
+	var receipts []Receipt
+	result := make(chan error, 1)
+	a.spawn(ctx, n, func(ctx context.Context) (err error) {
+		receipts, err = send(ctx, n)
+		return
+	}, func(err error) {
+		for _, torrent := range torrents {
+			a.delivered(n, torrent, receipts, err)
+		}
+		result <- err
+	})
+	a.mu.RUnlock()
+	select {
+	case err := <-result:
+		return receipts, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// send announces release, updated release is sent with Update
+// to producers, which are able to edit previously sent messages
+func (a *Announcer) send(ctx context.Context, n namedProducer, isNew bool, torrent *tts.TorrentInfo) ([]Receipt, error) {
+	if !isNew && n.Capabilities().Has(CapEdit) {
+		if u, ok := optional[Updater](n.ProducerV2); ok {
+			if previous := a.previous(n.id, torrent.Id); len(previous) > 0 {
+				return u.Update(ctx, torrent, previous)
+			}
+		}
+	}
+	return n.Send(ctx, isNew, torrent)
+}
+
+// previous returns receipts of last successful delivery of release to every target of producer
+func (a *Announcer) previous(producer string, torrent int64) []Receipt {
+	if a.db == nil {
+		return nil
+	}
+	records, err := a.db.GetDeliveryRecords(torrent)
+	if err != nil {
+		logger.Error("Unable to get delivery ledger of release ", torrent, ": ", err)
+		return nil
+	}
+	sent := func(r tts.DeliveryRecord) bool {
+		return r.Producer == producer && r.Status == DeliverySent && len(r.MessageId) > 0
+	}
+	last := make(map[string]time.Time)
+	for _, r := range records {
+		if sent(r) && r.Time.After(last[r.Target]) {
+			last[r.Target] = r.Time
+		}
+	}
+	var receipts []Receipt
+	for _, r := range records {
+		if sent(r) && r.Time.Equal(last[r.Target]) {
+			receipts = append(receipts, Receipt{Target: r.Target, MessageId: r.MessageId})
+		}
+	}
+	return receipts
+}
+
+func (a *Announcer) delivered(n namedProducer, torrent *tts.TorrentInfo, receipts []Receipt, err error) {
+	d := Delivery{Producer: n.id, Status: DeliverySent, Receipts: receipts}
+	if err != nil {
+		logger.Error("Producer ", n.id, " release ", torrent.Id, ": ", err)
+		d.Status, d.Error = DeliveryFailed, err.Error()
+	}
+	a.deliveries.set(torrent.Id, d)
+	if _, v1 := n.ProducerV2.(adapter); !v1 && !errors.Is(err, ErrCircuitOpen) {
+		a.record(n.id, torrent, receipts, err)
+	}
+}
+
+// record writes receipts and failed targets to delivery ledger
+func (a *Announcer) record(producer string, torrent *tts.TorrentInfo, receipts []Receipt, err error) {
+	if a.db == nil {
+		return
+	}
+	now := time.Now()
+	entry := tts.DeliveryRecord{
+		Torrent:  torrent.Id,
+		Revision: torrent.Revision,
+		Producer: producer,
+		Status:   DeliverySent,
+		Time:     now,
+	}
+	records := make([]tts.DeliveryRecord, 0, len(receipts)+1)
+	for _, r := range receipts {
+		entry.Target, entry.MessageId = r.Target, r.MessageId
+		records = append(records, entry)
+	}
+	entry.Status, entry.MessageId = DeliveryFailed, ""
+	for _, e := range unjoin(err) {
+		entry.Target, entry.Error = "", e.Error()
+		if de := (*DeliveryError)(nil); errors.As(e, &de) {
+			entry.Target, entry.Error = de.Target, de.Err.Error()
+		}
+		records = append(records, entry)
+	}
+	if len(records) == 0 {
+		return
+	}
+	if err = a.db.AddDeliveryRecords(records); err != nil {
+		logger.Error("Unable to write delivery ledger of release ", torrent.Id, ": ", err)
+	}
+}
+
+// unjoin returns list of errors joined in err
+func unjoin(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, unjoin(e)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
+
+// spawn submits fn to producer's queue, tracked by producer's in-flight counter.
+// Caller must hold a.mu and check that announcer is not closed, so queue is never waited for:
+// if it is full, done is called at once with temporary ErrQueueFull and outbox job is retried later
+func (a *Announcer) spawn(ctx context.Context, n namedProducer, fn func(context.Context) error, done func(error)) {
+	n.inflight.Add(1)
+	if !n.queue.push(func() {
+		defer n.inflight.Done()
+		err := a.run(ctx, n, fn)
+		if done != nil {
+			done(err)
+		}
+	}) {
+		n.inflight.Done()
+		if done != nil {
+			done(&DeliveryError{Temporary: true, Err: ErrQueueFull})
+		}
+	}
+}
+
+// run calls fn with ctx, which is also cancelled with announcer.
+// fn is skipped if ctx or announcer is cancelled before start,
+// or if producer is paused by circuit breaker.
+func (a *Announcer) run(ctx context.Context, n namedProducer, fn func(context.Context) error) error {
+	if wait := n.breaker.allow(n.conf.Breaker); wait > 0 {
+		return &DeliveryError{Temporary: true, RetryAfter: wait, Err: ErrCircuitOpen}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(a.ctx, cancel)()
+	err := ctx.Err()
+	if err == nil {
+		err = n.measure(func() error {
+			return fn(ctx)
+		})
+		if !errors.Is(err, context.Canceled) {
+			if n.breaker.done(n.conf.Breaker, err) {
+				logger.Warning("Producer ", n.id, " paused for ", n.breaker.allow(n.conf.Breaker), " after failures")
+				tts.Metrics.Set(tts.MetricCircuitOpen, 1, "producer", n.id)
+			} else if err == nil {
+				tts.Metrics.Set(tts.MetricCircuitOpen, 0, "producer", n.id)
+			}
+		}
+	}
+	return err
+}
+
+// Config returns config of active producer with provided id
+func (a *Announcer) Config(id string) (Config, bool) {
+	for _, n := range a.list() {
+		if n.id == id {
+			return n.conf, true
+		}
+	}
+	return Config{}, false
+}
+
+func (a *Announcer) Ids() []string {
+	list := a.list()
+	ids := make([]string, 0, len(list))
+	for _, n := range list {
+		ids = append(ids, n.id)
+	}
+	return ids
+}
+
+func (a *Announcer) SendNxGet(ctx context.Context, offset uint) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return
+	}
+	for _, n := range a.producers {
+		a.spawn(ctx, n, func(ctx context.Context) error {
+			return n.SendNxGet(ctx, offset)
+		}, func(err error) {
+			if err != nil {
+				logger.Error("Producer ", n.id, " offset ", offset, ": ", err)
+			}
+		})
+	}
+}
+
+func (n namedProducer) measure(fn func() error) (err error) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if err != nil {
+			tts.Metrics.Add(tts.MetricSendErrors, 1, "producer", n.id)
+		}
+		tts.Metrics.Observe(tts.MetricSendSeconds, time.Since(start).Seconds(), "producer", n.id)
+	}()
+	return fn()
+}
+
+func (n namedProducer) close() {
+	n.queue.stop()
+	if err := n.ProducerV2.Close(); err != nil {
+		logger.Error("Producer ", n.id, " close: ", err)
+	}
+}
+
+func (a *Announcer) Deliveries(id int64) []Delivery {
+	return a.deliveries.get(id)
+}
+
+func (a *Announcer) Health() error {
+	var errs []error
+	for _, n := range a.list() {
+		if hc, ok := optional[HealthChecker](n.ProducerV2); ok {
+			if err := hc.Healthy(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", n.id, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (a *Announcer) NotifyAdmins(ctx context.Context, msg string) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return
+	}
 	for _, n := range a.producers {
-		n.Close()
+		if an, ok := optional[AdminNotifier](n.ProducerV2); ok {
+			a.spawn(ctx, n, func(context.Context) error {
+				an.NotifyAdmins(msg)
+				return nil
+			}, nil)
+		}
+	}
+}
+
+// Shutdown stops accepting new sends and waits for in-flight ones.
+// If ctx is done before all sends are finished, they are cancelled and ctx error returned.
+func (a *Announcer) Shutdown(ctx context.Context) error {
+	a.mu.Lock()
+	a.closed = true
+	list := a.producers
+	a.mu.Unlock()
+	if err := wait(ctx, list); err != nil {
+		a.cancel()
+		return err
+	}
+	return nil
+}
+
+// Close closes every producer after its in-flight sends are finished,
+// sends which are not finished in closeTimeout are cancelled and waited for
+func (a *Announcer) Close() {
+	a.mu.Lock()
+	a.closed = true
+	list := a.producers
+	a.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	if err := wait(ctx, list); err != nil {
+		logger.Warning("In-flight sends not finished in time, cancelling: ", err)
+		a.cancel()
+		_ = wait(context.Background(), list)
+	}
+	a.cancel()
+	for _, n := range list {
+		n.close()
+		delete(producers, n.id)
+	}
+}
+
+// wait waits for in-flight sends of producers until ctx is done
+func wait(ctx context.Context, list []namedProducer) error {
+	done := make(chan struct{})
+	go func() {
+		for _, n := range list {
+			n.inflight.Wait()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
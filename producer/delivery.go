@@ -0,0 +1,93 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package producer
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DeliverySending = "sending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+
+	deliveryLogSize = 1000
+)
+
+type Delivery struct {
+	Producer string
+	Status   string
+	Time     time.Time
+	Receipts []Receipt
+	Error    string
+}
+
+// deliveryLog keeps statuses of last deliveries in memory
+type deliveryLog struct {
+	mu      sync.Mutex
+	order   []int64
+	entries map[int64]map[string]Delivery
+}
+
+func newDeliveryLog() *deliveryLog {
+	return &deliveryLog{
+		order:   make([]int64, 0, deliveryLogSize),
+		entries: make(map[int64]map[string]Delivery),
+	}
+}
+
+func (l *deliveryLog) set(id int64, d Delivery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, exist := l.entries[id]
+	if !exist {
+		if len(l.order) >= deliveryLogSize {
+			delete(l.entries, l.order[0])
+			l.order = l.order[1:]
+		}
+		l.order = append(l.order, id)
+		e = make(map[string]Delivery)
+		l.entries[id] = e
+	}
+	d.Time = time.Now()
+	e[d.Producer] = d
+}
+
+func (l *deliveryLog) get(id int64) []Delivery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	res := make([]Delivery, 0, len(l.entries[id]))
+	for _, d := range l.entries[id] {
+		res = append(res, d)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Producer < res[j].Producer
+	})
+	return res
+}
@@ -0,0 +1,114 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package producer
+
+import (
+	"context"
+	"errors"
+	"text/template"
+	"time"
+
+	tts "sot-te.ch/TTObserverV1/shared"
+)
+
+const (
+	MsgItems     = "items"
+	MsgCount     = "count"
+	MsgTotalSize = "totalsize"
+	MsgId        = "id"
+	MsgIsNew     = "isnew"
+)
+
+var errBatchNotSupported = errors.New("producer does not support digest")
+
+// DigestConfig sets collecting of releases for a window to send them in one message,
+// durations are in seconds
+type DigestConfig struct {
+	// Window is period to collect releases for, 0 - digest disabled
+	Window time.Duration `json:"window"`
+	// MaxItems is maximum count of releases in one message, 0 - unlimited
+	MaxItems uint `json:"maxitems"`
+	// Template is digest message template
+	Template string `json:"template"`
+}
+
+// BatchSender is implemented by producers with CapBatch, which send digest of several releases in one message
+type BatchSender interface {
+	SendBatch(ctx context.Context, msg string) ([]Receipt, error)
+}
+
+// DigestItem is release collected to digest
+type DigestItem struct {
+	IsNew   bool
+	Torrent *tts.TorrentInfo
+}
+
+func (d DigestConfig) compile() (*template.Template, error) {
+	if d.Window == 0 {
+		return nil, nil
+	}
+	if len(d.Template) == 0 {
+		return nil, tts.FieldError("", "digest.template", tts.ErrRequiredParameters)
+	}
+	t, err := template.New("digest").Parse(d.Template)
+	return t, tts.FieldError("", "digest.template", err)
+}
+
+// Next returns time, when digest with release enqueued at provided time is sent.
+// Windows are aligned to UTC, i.e. daily digest is sent at midnight
+func (d DigestConfig) Next(at time.Time) time.Time {
+	if d.Window == 0 {
+		return at
+	}
+	window := d.Window * time.Second
+	return at.Truncate(window).Add(window)
+}
+
+// formatDigest renders digest of items, template gets list of items (with `id`, `name`, `size`, `url`,
+// `filecount`, `meta` and `isnew`), count of items and their total size
+func formatDigest(tmpl *template.Template, items []DigestItem) (string, error) {
+	list := make([]map[string]any, 0, len(items))
+	var total uint64
+	for _, item := range items {
+		t := item.Torrent
+		total += t.Length
+		list = append(list, map[string]any{
+			MsgId:        t.Id,
+			MsgName:      t.Name,
+			MsgSize:      FormatFileSize(t.Length),
+			MsgUrl:       t.URL,
+			MsgFileCount: len(t.Files),
+			MsgMeta:      t.Meta,
+			MsgIsNew:     item.IsNew,
+		})
+	}
+	return FormatMessage(tmpl, map[string]any{
+		MsgItems:     list,
+		MsgCount:     len(items),
+		MsgTotalSize: FormatFileSize(total),
+	})
+}
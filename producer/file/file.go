@@ -29,7 +29,6 @@ package file
 import (
 	"crypto/sha1"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -55,7 +54,7 @@ var (
 )
 
 func init() {
-	producer.RegisterFactory("file", Notifier{})
+	producer.RegisterConfigFactory("file", Notifier{})
 }
 
 type Notifier struct {
@@ -65,33 +64,51 @@ type Notifier struct {
 	perm         uint64
 }
 
-func (Notifier) New(configPath string, _ s.Database) (producer.Producer, error) {
+func (Notifier) New(config []byte, _ s.Database) (producer.Producer, error) {
 	var err error
 	n := new(Notifier)
-	var confBytes []byte
-	if confBytes, err = os.ReadFile(filepath.Clean(configPath)); err == nil {
-		if err = json.Unmarshal(confBytes, n); err == nil {
-			var stat os.FileInfo
-			if stat, err = os.Stat(filepath.Dir(n.NameTemplate)); err == nil {
-				if stat.IsDir() {
-					if n.nameTemplate, err = tmpl.New(fmt.Sprint("file_", rand.Uint32())).Parse(n.NameTemplate); err == nil {
-						if len(n.Permissions) == 0 {
-							logger.Warning("Permissions parameter not set, falling to 0644")
-							n.perm = 0o644
-						} else {
-							n.perm, err = strconv.ParseUint(n.Permissions, 8, 32)
-						}
-					}
-				} else {
-					err = errInvalidPath
+	if err = s.DecodeConfig(config, n); err == nil {
+		err = n.prepare()
+	}
+	return n, err
+}
+
+// Validate checks name template, permissions and target directory
+func (Notifier) Validate(config []byte, _ s.Database) error {
+	n := new(Notifier)
+	err := s.DecodeConfig(config, n)
+	if err == nil {
+		err = n.prepare()
+	}
+	return err
+}
+
+func (n *Notifier) prepare() error {
+	stat, err := os.Stat(filepath.Dir(n.NameTemplate))
+	if err == nil {
+		if stat.IsDir() {
+			if n.nameTemplate, err = tmpl.New(fmt.Sprint("file_", rand.Uint32())).Parse(n.NameTemplate); err == nil {
+				if len(n.Permissions) == 0 {
+					logger.Warning("Permissions parameter not set, falling to 0644")
+					n.perm = 0o644
+				} else if n.perm, err = strconv.ParseUint(n.Permissions, 8, 32); err != nil {
+					err = s.FieldError("", "permissions", err)
 				}
 			}
+		} else {
+			err = errInvalidPath
 		}
 	}
-	return n, err
+	return err
+}
+
+func (fl Notifier) Send(isNew bool, torrent *s.TorrentInfo) {
+	if err := fl.TrySend(isNew, torrent); err != nil {
+		logger.Error(err)
+	}
 }
 
-func (fl Notifier) Send(_ bool, torrent *s.TorrentInfo) {
+func (fl Notifier) TrySend(_ bool, torrent *s.TorrentInfo) error {
 	var err error
 	var fileName string
 	hash := sha1.New()
@@ -108,9 +125,7 @@ func (fl Notifier) Send(_ bool, torrent *s.TorrentInfo) {
 			err = errors.New("filename is empty")
 		}
 	}
-	if err != nil {
-		logger.Error(err)
-	}
+	return err
 }
 
 func (Notifier) Close() {}
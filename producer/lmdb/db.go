@@ -29,7 +29,6 @@ package lmdb
 import (
 	"crypto/sha1"
 	"crypto/sha256"
-	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
@@ -45,7 +44,7 @@ import (
 var logger = logging.MustGetLogger("lmdb")
 
 func init() {
-	producer.RegisterFactory("lmdb", conf{})
+	producer.RegisterConfigFactory("lmdb", conf{})
 }
 
 type conf struct {
@@ -65,19 +64,45 @@ type mdb struct {
 	prefix []byte
 }
 
-func (conf) New(path string, _ s.Database) (producer.Producer, error) {
+func (conf) New(config []byte, _ s.Database) (producer.Producer, error) {
 	var err error
 	cfg := new(conf)
 	var db *mdb
-	var confBytes []byte
-	if confBytes, err = os.ReadFile(filepath.Clean(path)); err == nil {
-		if err = json.Unmarshal(confBytes, cfg); err == nil {
-			db, err = newDB(cfg)
-		}
+	if err = s.DecodeConfig(config, cfg); err == nil {
+		db, err = newDB(cfg)
 	}
 	return db, err
 }
 
+// Validate checks required parameters and database directory without opening environment,
+// as it may be used by running instance
+func (conf) Validate(config []byte, _ s.Database) error {
+	cfg := new(conf)
+	err := s.DecodeConfig(config, cfg)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	if len(cfg.Path) == 0 {
+		errs = append(errs, s.FieldError("", "path", s.ErrRequiredParameters))
+	} else {
+		dir := cfg.Path
+		if _, err = os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+			// directory is created on start
+			dir = filepath.Dir(dir)
+		}
+		if stat, err := os.Stat(dir); err != nil {
+			errs = append(errs, s.FieldError("", "path", err))
+		} else if !stat.IsDir() {
+			errs = append(errs, s.FieldError("", "path", errors.New("not a directory: "+dir)))
+		}
+	}
+	if len(cfg.DBName) == 0 {
+		errs = append(errs, s.FieldError("", "dbname", s.ErrRequiredParameters))
+	}
+	return errors.Join(errs...)
+}
+
 func newDB(cfg *conf) (db *mdb, err error) {
 	if len(cfg.Path) > 0 && len(cfg.DBName) > 0 {
 		db = &mdb{calcV2: cfg.CalculateV2, prefix: []byte(cfg.KeyPrefix)}
@@ -123,7 +148,13 @@ func newDB(cfg *conf) (db *mdb, err error) {
 	return
 }
 
-func (d *mdb) Send(_ bool, t *s.TorrentInfo) {
+func (d *mdb) Send(isNew bool, t *s.TorrentInfo) {
+	if err := d.TrySend(isNew, t); err != nil {
+		logger.Error(err)
+	}
+}
+
+func (d *mdb) TrySend(_ bool, t *s.TorrentInfo) error {
 	var err error
 	var h1, h2 []byte
 	if h1, h2, err = s.GenerateTorrentInfoHash(t.Data, d.calcV2); err == nil {
@@ -141,9 +172,7 @@ func (d *mdb) Send(_ bool, t *s.TorrentInfo) {
 			return
 		})
 	}
-	if err != nil {
-		logger.Error(err)
-	}
+	return err
 }
 
 func (*mdb) SendNxGet(uint) {}
@@ -28,11 +28,10 @@ package nats
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
-	"encoding/json"
 	"errors"
-	"os"
-	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -42,7 +41,10 @@ import (
 	s "sot-te.ch/TTObserverV1/shared"
 )
 
-var logger = logging.MustGetLogger("nats")
+var (
+	logger          = logging.MustGetLogger("nats")
+	errNotConnected = errors.New("not connected")
+)
 
 const (
 	maxMessageSize        = 10485760 // 10 MiB
@@ -52,7 +54,7 @@ const (
 )
 
 func init() {
-	producer.RegisterFactory("nats", new(Notifier))
+	producer.RegisterFactoryV2("nats", new(Notifier))
 }
 
 type Notifier struct {
@@ -123,42 +125,78 @@ func (nc *Notifier) init() error {
 	return err
 }
 
-func (*Notifier) New(configPath string, db s.Database) (producer.Producer, error) {
+func (*Notifier) New(config []byte, db s.Database) (producer.ProducerV2, error) {
 	var err error
 	n := &Notifier{db: db}
-	var confBytes []byte
-	if confBytes, err = os.ReadFile(filepath.Clean(configPath)); err == nil {
-		if err = json.Unmarshal(confBytes, n); err == nil {
-			err = n.init()
-		}
+	if err = s.DecodeConfig(config, n); err == nil {
+		s.AddDSNSecret(n.URL)
+		err = n.init()
 	}
 	return n, err
 }
 
-func (nc *Notifier) Send(_ bool, torrent *s.TorrentInfo) {
-	var err error
+// Validate checks required parameters and server availability, stream is not created
+func (*Notifier) Validate(config []byte, _ s.Database) error {
+	n := new(Notifier)
+	if err := s.DecodeConfig(config, n); err != nil {
+		return err
+	}
+	s.AddDSNSecret(n.URL)
+	var errs []error
+	if len(n.Subject) == 0 {
+		errs = append(errs, s.FieldError("", "subject", s.ErrRequiredParameters))
+	}
+	if len(n.URL) == 0 {
+		errs = append(errs, s.FieldError("", "url", s.ErrRequiredParameters))
+	} else if client, err := nats.Connect(n.URL); err == nil {
+		client.Close()
+	} else {
+		errs = append(errs, s.FieldError("", "url", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (nc *Notifier) Send(ctx context.Context, _ bool, torrent *s.TorrentInfo) ([]producer.Receipt, error) {
 	bb := new(bytes.Buffer)
 	enc := gob.NewEncoder(bb)
-	if err = enc.Encode(torrent); err == nil {
-		msg := &nats.Msg{
-			Subject: nc.Subject,
-			Data:    bb.Bytes(),
-		}
-		if nc.js == nil {
-			err = nc.client.PublishMsg(msg)
-		} else {
-			_, err = nc.js.PublishMsg(msg)
+	if err := enc.Encode(torrent); err != nil {
+		return nil, &producer.DeliveryError{Err: err}
+	}
+	msg := &nats.Msg{
+		Subject: nc.Subject,
+		Data:    bb.Bytes(),
+	}
+	if nc.js == nil {
+		if err := nc.client.PublishMsg(msg); err != nil {
+			return nil, &producer.DeliveryError{Target: nc.Subject, Temporary: true, Err: err}
 		}
+		return []producer.Receipt{{Target: nc.Subject}}, nil
 	}
+	ack, err := nc.js.PublishMsg(msg, nats.Context(ctx))
 	if err != nil {
-		logger.Error(err)
+		return nil, &producer.DeliveryError{Target: nc.Stream, Temporary: true, Err: err}
 	}
+	return []producer.Receipt{{Target: ack.Stream, MessageId: strconv.FormatUint(ack.Sequence, 10)}}, nil
 }
 
-func (nc *Notifier) Close() {
+func (nc *Notifier) Healthy() error {
+	if nc.client == nil || !nc.client.IsConnected() {
+		return errNotConnected
+	}
+	return nil
+}
+
+func (*Notifier) Capabilities() producer.Capability {
+	return 0
+}
+
+func (nc *Notifier) Close() error {
 	if nc.client != nil {
 		nc.client.Close()
 	}
+	return nil
 }
 
-func (*Notifier) SendNxGet(uint) {}
+func (*Notifier) SendNxGet(context.Context, uint) error {
+	return nil
+}
@@ -27,33 +27,208 @@
 package producer
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"sync"
+	"time"
 
 	tts "sot-te.ch/TTObserverV1/shared"
 )
 
 type Config struct {
-	Id         string `json:"id"`
-	Type       string `json:"type"`
-	ConfigPath string `json:"configpath"`
+	Id         string          `json:"id"`
+	Type       string          `json:"type"`
+	ConfigPath string          `json:"configpath,omitempty"`
+	Config     json.RawMessage `json:"config,omitempty"`
+	// Concurrency is count of releases sent by producer at once, 1 if not set
+	Concurrency uint          `json:"concurrency"`
+	Retry       RetryPolicy   `json:"retry"`
+	Breaker     BreakerPolicy `json:"breaker"`
+	Digest      DigestConfig  `json:"digest"`
 }
 
+// Producer is the first version of producer interface, it is wrapped
+// to ProducerV2 with adapter (see Adapt)
 type Producer interface {
 	Send(bool, *tts.TorrentInfo)
 	SendNxGet(uint)
 	Close()
 }
 
+// Capability is a set of optional features supported by ProducerV2
+type Capability uint
+
+const (
+	// CapEdit - producer is able to edit sent message
+	CapEdit Capability = 1 << iota
+	// CapDelete - producer is able to delete sent message
+	CapDelete
+	// CapMedia - producer sends release images
+	CapMedia
+	// CapBatch - producer is able to send several releases in one message
+	CapBatch
+)
+
+func (c Capability) Has(other Capability) bool {
+	return c&other == other
+}
+
+// Receipt identifies message sent by producer
+type Receipt struct {
+	// Target is destination of message: chat, group, key, etc.
+	Target string `json:"target"`
+	// MessageId is id of message in target
+	MessageId string `json:"messageid"`
+}
+
+// DeliveryError is returned by ProducerV2 when release is not delivered
+type DeliveryError struct {
+	// Target is destination failed, empty if send failed entirely
+	Target string
+	// Temporary is true if send may succeed later (network error, rate limit, etc.)
+	Temporary bool
+	// RetryAfter is delay requested by backend before next try, if known
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *DeliveryError) Error() string {
+	if len(e.Target) > 0 {
+		return e.Target + ": " + e.Err.Error()
+	}
+	return e.Err.Error()
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// IsTemporary returns true if any of errors in err is temporary DeliveryError,
+// network timeout or context deadline
+func IsTemporary(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if IsTemporary(e) {
+				return true
+			}
+		}
+		return false
+	}
+	var de *DeliveryError
+	var ne net.Error
+	if errors.As(err, &de) {
+		return de.Temporary
+	}
+	return errors.As(err, &ne) && ne.Timeout() || errors.Is(err, context.DeadlineExceeded)
+}
+
+// ProducerV2 sends releases and reports results
+type ProducerV2 interface {
+	// Send announces release, returns receipts of sent messages
+	// and DeliveryError (may be joined, one per target) on failure
+	Send(ctx context.Context, isNew bool, torrent *tts.TorrentInfo) ([]Receipt, error)
+	SendNxGet(ctx context.Context, offset uint) error
+	Capabilities() Capability
+	Close() error
+}
+
+type AdminNotifier interface {
+	NotifyAdmins(string)
+}
+
+type HealthChecker interface {
+	Healthy() error
+}
+
+// Updater is implemented by producers with CapEdit, which edit already sent messages
+// when release is updated
+type Updater interface {
+	// Update announces updated release, previous are receipts of last delivery of release.
+	// Targets without previous receipts get new message
+	Update(ctx context.Context, torrent *tts.TorrentInfo, previous []Receipt) ([]Receipt, error)
+}
+
+// Controller is a part of observer, which may be controlled by producers (i.e. by admin commands)
+type Controller interface {
+	// Reannounce enqueues release to producers, or to every producer if list is empty
+	Reannounce(id int64, producers []string) error
+	SetOffset(offset uint) error
+	Pause()
+	Resume()
+	Paused() bool
+}
+
+// Controlled is implemented by producers, which need access to observer
+type Controlled interface {
+	SetController(Controller)
+}
+
+// Update modes of producers supporting edit
+const (
+	// UpdateNew - updated release is announced with new message
+	UpdateNew = "new"
+	// UpdateEdit - previous message is edited
+	UpdateEdit = "edit"
+	// UpdateEditReply - previous message is edited and short reply is sent to it
+	UpdateEditReply = "editreply"
+)
+
+var errUpdateMode = errors.New("must be one of: " + UpdateNew + ", " + UpdateEdit + ", " + UpdateEditReply)
+
+// CheckUpdateMode returns error if mode is unknown, empty mode is UpdateNew
+func CheckUpdateMode(mode string) error {
+	switch mode {
+	case "", UpdateNew, UpdateEdit, UpdateEditReply:
+		return nil
+	}
+	return errUpdateMode
+}
+
+// Factory constructs producer from config file located at path
 type Factory interface {
 	New(string, tts.Database) (Producer, error)
 }
 
+// ConfigFactory constructs producer from json config, inline or loaded from file
+// with expanded references
+type ConfigFactory interface {
+	New([]byte, tts.Database) (Producer, error)
+}
+
+// FactoryV2 constructs ProducerV2 from json config
+type FactoryV2 interface {
+	New([]byte, tts.Database) (ProducerV2, error)
+}
+
+// Validator is implemented by factories able to check json configuration
+// and backend availability without starting producer
+type Validator interface {
+	Validate([]byte, tts.Database) error
+}
+
 var (
-	factories   = make(map[string]Factory)
+	factories   = make(map[string]any)
 	factoriesMu sync.Mutex
 )
 
+var errInlineConfig = errors.New("producer type does not support inline config, use configpath")
+
 func RegisterFactory(name string, n Factory) {
+	register(name, n)
+}
+
+func RegisterConfigFactory(name string, n ConfigFactory) {
+	register(name, n)
+}
+
+func RegisterFactoryV2(name string, n FactoryV2) {
+	register(name, n)
+}
+
+func register(name string, n any) {
 	factoriesMu.Lock()
 	defer factoriesMu.Unlock()
 	if len(name) == 0 {
@@ -65,3 +240,68 @@ func RegisterFactory(name string, n Factory) {
 		factories[name] = n
 	}
 }
+
+// Check validates producer configuration and backend availability.
+// Producers are never started, if factory does not implement Validator, only policies are checked.
+func Check(conf Config, db tts.Database) error {
+	fac := factories[conf.Type]
+	if fac == nil {
+		return fmt.Errorf("unknown type: %s", conf.Type)
+	}
+	_, digestErr := conf.Digest.compile()
+	policyErr := errors.Join(conf.Retry.check(), digestErr)
+	data, err := conf.load()
+	if err != nil {
+		return errors.Join(policyErr, err)
+	}
+	if v, ok := fac.(Validator); ok {
+		err = v.Validate(data, db)
+	} else {
+		logger.Warning("Producer ", conf.Id, " of type ", conf.Type, " does not support validation, skipping")
+	}
+	return errors.Join(policyErr, conf.locate(err))
+}
+
+func construct(fac any, conf Config, data []byte, db tts.Database) (ProducerV2, error) {
+	var p Producer
+	var err error
+	switch f := fac.(type) {
+	case FactoryV2:
+		return f.New(data, db)
+	case ConfigFactory:
+		p, err = f.New(data, db)
+	case Factory:
+		if len(conf.ConfigPath) == 0 {
+			return nil, errInlineConfig
+		}
+		p, err = f.New(conf.ConfigPath, db)
+	default:
+		return nil, errors.New("invalid factory")
+	}
+	if p == nil {
+		return nil, err
+	}
+	return Adapt(p), err
+}
+
+// load returns inline config if set, or content of config file
+func (conf Config) load() ([]byte, error) {
+	if len(conf.Config) > 0 {
+		return conf.Config, nil
+	} else if len(conf.ConfigPath) > 0 {
+		return tts.LoadConfig(conf.ConfigPath)
+	}
+	return nil, tts.FieldError("", "config, configpath", tts.ErrRequiredParameters)
+}
+
+// locate sets config file path to config errors returned by factory
+func (conf Config) locate(err error) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			conf.locate(e)
+		}
+	} else if ce := (*tts.ConfigError)(nil); errors.As(err, &ce) && len(ce.Path) == 0 {
+		ce.Path = conf.ConfigPath
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package producer
+
+import (
+	"errors"
+	"sync"
+)
+
+// queueSize is count of tasks, which may wait for producer's worker,
+// submitting more fails with ErrQueueFull
+const queueSize = 1000
+
+var ErrQueueFull = errors.New("producer queue is full")
+
+// queue runs producer's tasks in order of submission by fixed count of workers
+type queue struct {
+	tasks chan func()
+	once  *sync.Once
+}
+
+func newQueue(workers uint) queue {
+	q := queue{tasks: make(chan func(), queueSize), once: new(sync.Once)}
+	for range max(workers, 1) {
+		go func() {
+			for task := range q.tasks {
+				task()
+			}
+		}()
+	}
+	return q
+}
+
+// push submits task without waiting, returns false if queue is full
+func (q queue) push(task func()) bool {
+	select {
+	case q.tasks <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop finishes workers after already submitted tasks are run
+func (q queue) stop() {
+	q.once.Do(func() {
+		close(q.tasks)
+	})
+}
@@ -29,9 +29,7 @@ package redis
 import (
 	"context"
 	"crypto/sha1"
-	"encoding/json"
-	"os"
-	"path/filepath"
+	"errors"
 
 	"github.com/op/go-logging"
 	"github.com/redis/go-redis/v9"
@@ -53,7 +51,7 @@ const (
 )
 
 func init() {
-	producer.RegisterFactory("redis", new(Notifier))
+	producer.RegisterConfigFactory("redis", new(Notifier))
 }
 
 type Notifier struct {
@@ -66,36 +64,65 @@ type Notifier struct {
 	con           *redis.Client
 }
 
-func (*Notifier) New(configPath string, _ s.Database) (producer.Producer, error) {
+func (*Notifier) New(config []byte, _ s.Database) (producer.Producer, error) {
 	var err error
 	n := new(Notifier)
-	var confBytes []byte
-	if confBytes, err = os.ReadFile(filepath.Clean(configPath)); err == nil {
-		if err = json.Unmarshal(confBytes, n); err == nil {
-			if len(n.Address) > 0 && len(n.HashKey) > 0 {
-				n.con = redis.NewClient(&redis.Options{
-					Addr:     n.Address,
-					Password: n.Password,
-					DB:       n.DB,
-				})
-				err = n.con.Ping(ctx).Err()
-			} else {
-				err = s.ErrRequiredParameters
-			}
-			if len(n.NameKeyPrefix) == 0 {
-				logger.Warning("Name key prefix not set, using default: ", defaultNamePrefix)
-				n.NameKeyPrefix = defaultNamePrefix
-			}
+	if err = s.DecodeConfig(config, n); err == nil {
+		s.AddSecret(n.Password)
+		if len(n.Address) > 0 && len(n.HashKey) > 0 {
+			n.con = redis.NewClient(&redis.Options{
+				Addr:     n.Address,
+				Password: n.Password,
+				DB:       n.DB,
+			})
+			err = n.con.Ping(ctx).Err()
+		} else {
+			err = s.ErrRequiredParameters
+		}
+		if len(n.NameKeyPrefix) == 0 {
+			logger.Warning("Name key prefix not set, using default: ", defaultNamePrefix)
+			n.NameKeyPrefix = defaultNamePrefix
 		}
 	}
 	return n, err
 }
 
+// Validate checks required parameters and server availability
+func (*Notifier) Validate(config []byte, _ s.Database) error {
+	n := new(Notifier)
+	if err := s.DecodeConfig(config, n); err != nil {
+		return err
+	}
+	s.AddSecret(n.Password)
+	var errs []error
+	if len(n.HashKey) == 0 {
+		errs = append(errs, s.FieldError("", "hashkey", s.ErrRequiredParameters))
+	}
+	if len(n.Address) == 0 {
+		errs = append(errs, s.FieldError("", "address", s.ErrRequiredParameters))
+	} else {
+		con := redis.NewClient(&redis.Options{
+			Addr:     n.Address,
+			Password: n.Password,
+			DB:       n.DB,
+		})
+		errs = append(errs, s.FieldError("", "address", con.Ping(ctx).Err()))
+		_ = con.Close()
+	}
+	return errors.Join(errs...)
+}
+
 func (r *Notifier) Send(isNew bool, t *s.TorrentInfo) {
+	if err := r.TrySend(isNew, t); err != nil {
+		logger.Error(err)
+	}
+}
+
+func (r *Notifier) TrySend(isNew bool, t *s.TorrentInfo) error {
 	torrentNameKey := r.NameKeyPrefix + t.Name
 	if !isNew {
 		if prevHashes, err := r.con.HMGet(ctx, torrentNameKey, v1Field, v2Field, hybridField).Result(); err != nil {
-			logger.Error(err)
+			return err
 		} else {
 			if len(prevHashes) > 0 {
 				fields := make([]string, 0, len(prevHashes))
@@ -110,7 +137,7 @@ func (r *Notifier) Send(isNew bool, t *s.TorrentInfo) {
 				}
 				if len(fields) > 0 {
 					if err = r.con.HDel(ctx, r.HashKey, fields...).Err(); err != nil {
-						logger.Error(err)
+						return err
 					}
 				}
 			}
@@ -133,9 +160,11 @@ func (r *Notifier) Send(isNew bool, t *s.TorrentInfo) {
 			err = r.con.HSet(ctx, torrentNameKey, values...).Err()
 		}
 	}
-	if err != nil {
-		logger.Error(err)
-	}
+	return err
+}
+
+func (r *Notifier) Healthy() error {
+	return r.con.Ping(ctx).Err()
 }
 
 func (r *Notifier) Close() {
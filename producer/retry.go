@@ -0,0 +1,147 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package producer
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	tts "sot-te.ch/TTObserverV1/shared"
+)
+
+const (
+	defaultAttempts   = 10
+	defaultBackoff    = 10
+	defaultMaxBackoff = 3600
+	defaultCooldown   = 60
+)
+
+// ErrCircuitOpen is returned instead of sending, while producer is paused by circuit breaker
+var ErrCircuitOpen = errors.New("circuit open")
+
+// RetryPolicy sets how failed delivery is retried, durations are in seconds
+type RetryPolicy struct {
+	// Attempts is maximum count of delivery attempts, after that job is moved to dead-letter
+	Attempts uint `json:"attempts"`
+	// Backoff is delay before second attempt, doubled for every next one
+	Backoff time.Duration `json:"backoff"`
+	// MaxBackoff is maximum delay between attempts
+	MaxBackoff time.Duration `json:"maxbackoff"`
+	// Jitter is part of delay (0-1) randomly added or subtracted
+	Jitter float64 `json:"jitter"`
+}
+
+// BreakerPolicy sets when producer is paused after consecutive failures, durations are in seconds
+type BreakerPolicy struct {
+	// Threshold is count of consecutive failures to pause producer, 0 - breaker disabled
+	Threshold uint `json:"threshold"`
+	// Cooldown is time producer is paused for
+	Cooldown time.Duration `json:"cooldown"`
+}
+
+func (p RetryPolicy) check() error {
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return tts.FieldError("", "retry.jitter", errors.New("must be between 0 and 1"))
+	}
+	if p.MaxBackoff > 0 && p.Backoff > p.MaxBackoff {
+		return tts.FieldError("", "retry.backoff", errors.New("greater than maxbackoff"))
+	}
+	return nil
+}
+
+// Exhausted returns true if no more attempts allowed
+func (p RetryPolicy) Exhausted(attempts uint) bool {
+	limit := p.Attempts
+	if limit == 0 {
+		limit = defaultAttempts
+	}
+	return attempts >= limit
+}
+
+// Delay returns time to wait before next attempt, after provided count of attempts made
+func (p RetryPolicy) Delay(attempts uint) time.Duration {
+	backoff, limit := p.Backoff, p.MaxBackoff
+	if backoff == 0 {
+		backoff = defaultBackoff
+	}
+	if limit == 0 {
+		limit = defaultMaxBackoff
+	}
+	backoff, limit = backoff*time.Second, limit*time.Second
+	d := backoff
+	for i := uint(1); i < attempts && d < limit; i++ {
+		d *= 2
+	}
+	d = min(d, limit)
+	if p.Jitter > 0 {
+		d += time.Duration(float64(d) * min(p.Jitter, 1) * (2*rand.Float64() - 1))
+	}
+	return d
+}
+
+// breaker counts consecutive failures of producer and pauses it
+type breaker struct {
+	mu       sync.Mutex
+	failures uint
+	until    time.Time
+}
+
+// allow returns zero if call is allowed, or time left until producer resumes
+func (b *breaker) allow(p BreakerPolicy) time.Duration {
+	if p.Threshold == 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return max(time.Until(b.until), 0)
+}
+
+// done records result of call, returns true if breaker opened.
+// Breaker opens on threshold failures and re-opens on first failure after cooldown
+func (b *breaker) done(p BreakerPolicy, err error) bool {
+	if p.Threshold == 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.failures, b.until = 0, time.Time{}
+		return false
+	}
+	b.failures++
+	if b.failures >= p.Threshold {
+		cooldown := p.Cooldown
+		if cooldown == 0 {
+			cooldown = defaultCooldown
+		}
+		b.until = time.Now().Add(cooldown * time.Second)
+		return true
+	}
+	return false
+}
@@ -29,9 +29,6 @@ package sqldb
 import (
 	"crypto/sha1"
 	"database/sql"
-	"encoding/json"
-	"os"
-	"path/filepath"
 
 	"github.com/op/go-logging"
 
@@ -42,7 +39,7 @@ import (
 var logger = logging.MustGetLogger("sqldb")
 
 func init() {
-	producer.RegisterFactory("sqldb", DB{})
+	producer.RegisterConfigFactory("sqldb", DB{})
 }
 
 type DB struct {
@@ -53,27 +50,38 @@ type DB struct {
 	CalculateV2 bool   `json:"calculatev2"`
 }
 
-func (DB) New(path string, _ s.Database) (producer.Producer, error) {
+func (DB) New(config []byte, _ s.Database) (producer.Producer, error) {
 	var err error
 	n := new(DB)
-	var confBytes []byte
-	if confBytes, err = os.ReadFile(filepath.Clean(path)); err == nil {
-		if err = json.Unmarshal(confBytes, n); err == nil {
-			if len(n.Driver) > 0 && len(n.Address) > 0 && len(n.DeleteQuery) > 0 {
-				var con *sql.DB
-				if con, err = sql.Open(n.Driver, n.Address); err == nil {
-					defer con.Close()
-					err = con.Ping()
-				}
-			} else {
-				err = s.ErrRequiredParameters
+	if err = s.DecodeConfig(config, n); err == nil {
+		s.AddDSNSecret(n.Address)
+		if len(n.Driver) > 0 && len(n.Address) > 0 && len(n.DeleteQuery) > 0 {
+			var con *sql.DB
+			if con, err = sql.Open(n.Driver, n.Address); err == nil {
+				defer con.Close()
+				err = con.Ping()
 			}
+		} else {
+			err = s.ErrRequiredParameters
 		}
 	}
 	return n, err
 }
 
-func (d DB) Send(_ bool, t *s.TorrentInfo) {
+// Validate checks required parameters and database availability,
+// producer does not keep connection, so it is the same as New
+func (DB) Validate(config []byte, _ s.Database) error {
+	_, err := DB{}.New(config, nil)
+	return err
+}
+
+func (d DB) Send(isNew bool, t *s.TorrentInfo) {
+	if err := d.TrySend(isNew, t); err != nil {
+		logger.Error(err)
+	}
+}
+
+func (d DB) TrySend(_ bool, t *s.TorrentInfo) error {
 	var err error
 	var h1, h2 []byte
 	if h1, h2, err = s.GenerateTorrentInfoHash(t.Data, d.CalculateV2); err == nil {
@@ -83,9 +91,7 @@ func (d DB) Send(_ bool, t *s.TorrentInfo) {
 			err = d.ExecDB(con, t.Name, h1, h2)
 		}
 	}
-	if err != nil {
-		logger.Error(err)
-	}
+	return err
 }
 
 func (d DB) ExecDB(con *sql.DB, name string, h1, h2 []byte) (err error) {
@@ -29,13 +29,11 @@ package stan
 import (
 	"bytes"
 	"encoding/gob"
-	"encoding/json"
 	"errors"
-	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/nats-io/nats.go"
 	"github.com/nats-io/stan.go"
 	"github.com/op/go-logging"
 
@@ -49,7 +47,7 @@ var (
 )
 
 func init() {
-	producer.RegisterFactory("stan", new(Notifier))
+	producer.RegisterConfigFactory("stan", new(Notifier))
 }
 
 type Notifier struct {
@@ -96,26 +94,55 @@ func (st *Notifier) init() error {
 	return err
 }
 
-func (*Notifier) New(configPath string, db s.Database) (producer.Producer, error) {
+func (*Notifier) New(config []byte, db s.Database) (producer.Producer, error) {
 	var err error
 	n := &Notifier{
 		db:              db,
 		reconnectWaiter: &sync.WaitGroup{},
 	}
-	var confBytes []byte
-	if confBytes, err = os.ReadFile(filepath.Clean(configPath)); err == nil {
-		if err = json.Unmarshal(confBytes, n); err == nil {
-			if len(n.ClusterId) > 0 && len(n.ClientId) > 0 && len(n.URL) > 0 {
-				err = n.init()
-			} else {
-				err = errors.New("required parameters not set")
-			}
+	if err = s.DecodeConfig(config, n); err == nil {
+		s.AddDSNSecret(n.URL)
+		if len(n.ClusterId) > 0 && len(n.ClientId) > 0 && len(n.URL) > 0 {
+			err = n.init()
+		} else {
+			err = errors.New("required parameters not set")
 		}
 	}
 	return n, err
 }
 
-func (st *Notifier) Send(_ bool, torrent *s.TorrentInfo) {
+// Validate checks required parameters and server availability.
+// Streaming client is not connected, as client id may be taken by running instance
+func (*Notifier) Validate(config []byte, _ s.Database) error {
+	n := new(Notifier)
+	if err := s.DecodeConfig(config, n); err != nil {
+		return err
+	}
+	s.AddDSNSecret(n.URL)
+	var errs []error
+	if len(n.ClusterId) == 0 {
+		errs = append(errs, s.FieldError("", "clusterid", s.ErrRequiredParameters))
+	}
+	if len(n.ClientId) == 0 {
+		errs = append(errs, s.FieldError("", "clientid", s.ErrRequiredParameters))
+	}
+	if len(n.URL) == 0 {
+		errs = append(errs, s.FieldError("", "url", s.ErrRequiredParameters))
+	} else if client, err := nats.Connect(n.URL); err == nil {
+		client.Close()
+	} else {
+		errs = append(errs, s.FieldError("", "url", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (st *Notifier) Send(isNew bool, torrent *s.TorrentInfo) {
+	if err := st.TrySend(isNew, torrent); err != nil {
+		logger.Error(err)
+	}
+}
+
+func (st *Notifier) TrySend(_ bool, torrent *s.TorrentInfo) error {
 	var err error
 	bb := new(bytes.Buffer)
 	enc := gob.NewEncoder(bb)
@@ -128,9 +155,7 @@ func (st *Notifier) Send(_ bool, torrent *s.TorrentInfo) {
 			}
 		}
 	}
-	if err != nil {
-		logger.Error(err)
-	}
+	return err
 }
 
 func (st *Notifier) Close() {
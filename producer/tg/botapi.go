@@ -0,0 +1,264 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package tg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	defaultBotAPIURL = "https://api.telegram.org"
+	maxAlbumSize     = 10
+	maxCaptionLength = 1024
+	// maxDocumentSize is upload limit of Bot API
+	maxDocumentSize = 50 << 20
+)
+
+type botAPIResponse struct {
+	Ok          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+type botAPIError struct {
+	method      string
+	code        int
+	description string
+	retryAfter  time.Duration
+}
+
+func (e *botAPIError) Error() string {
+	return fmt.Sprintf("bot api %s: %d %s", e.method, e.code, e.description)
+}
+
+// temporary returns true for rate limit and server side errors
+func (e *botAPIError) temporary() bool {
+	return e.code == http.StatusTooManyRequests || e.code >= http.StatusInternalServerError
+}
+
+// notModified returns true if edited message is the same as existing one
+func (e *botAPIError) notModified() bool {
+	return e.code == http.StatusBadRequest && strings.Contains(e.description, "message is not modified")
+}
+
+type botAPIMessage struct {
+	MessageId int64 `json:"message_id"`
+}
+
+type inputMedia struct {
+	Type      string `json:"type"`
+	Media     string `json:"media"`
+	Caption   string `json:"caption,omitempty"`
+	ParseMode string `json:"parse_mode,omitempty"`
+}
+
+type botAPI struct {
+	client *resty.Client
+}
+
+func newBotAPI(baseURL, token string) *botAPI {
+	if len(baseURL) == 0 {
+		baseURL = defaultBotAPIURL
+	}
+	return &botAPI{
+		client: resty.New().
+			SetBaseURL(fmt.Sprintf("%s/bot%s", baseURL, token)).
+			SetTimeout(time.Minute),
+	}
+}
+
+func (b *botAPI) call(ctx context.Context, method string, req *resty.Request, result any) error {
+	resp, err := req.SetContext(ctx).Post(method)
+	if err == nil {
+		apiResp := new(botAPIResponse)
+		if err = json.Unmarshal(resp.Body(), apiResp); err == nil {
+			if apiResp.Ok {
+				if result != nil {
+					err = json.Unmarshal(apiResp.Result, result)
+				}
+			} else {
+				err = &botAPIError{
+					method:      method,
+					code:        apiResp.ErrorCode,
+					description: apiResp.Description,
+					retryAfter:  time.Duration(apiResp.Parameters.RetryAfter) * time.Second,
+				}
+			}
+		}
+	}
+	return err
+}
+
+func (b *botAPI) sendMessage(ctx context.Context, chat int64, text, parseMode string) (int64, error) {
+	msg := new(botAPIMessage)
+	err := b.call(ctx, "sendMessage", b.client.R().SetFormData(map[string]string{
+		"chat_id":    strconv.FormatInt(chat, 10),
+		"text":       text,
+		"parse_mode": parseMode,
+	}), msg)
+	return msg.MessageId, err
+}
+
+func (b *botAPI) sendPhoto(ctx context.Context, chat int64, photo []byte, caption, parseMode string) (int64, error) {
+	msg := new(botAPIMessage)
+	err := b.call(ctx, "sendPhoto", b.client.R().SetFormData(map[string]string{
+		"chat_id":    strconv.FormatInt(chat, 10),
+		"caption":    caption,
+		"parse_mode": parseMode,
+	}).SetFileReader("photo", "photo.jpg", bytes.NewReader(photo)), msg)
+	return msg.MessageId, err
+}
+
+func (b *botAPI) sendDocument(ctx context.Context, chat int64, name string, data []byte, caption, parseMode string) (int64, error) {
+	msg := new(botAPIMessage)
+	err := b.call(ctx, "sendDocument", b.client.R().SetFormData(map[string]string{
+		"chat_id":    strconv.FormatInt(chat, 10),
+		"caption":    caption,
+		"parse_mode": parseMode,
+	}).SetFileReader("document", name, bytes.NewReader(data)), msg)
+	return msg.MessageId, err
+}
+
+func (b *botAPI) sendMediaGroup(ctx context.Context, chat int64, images [][]byte, caption, parseMode string) ([]int64, error) {
+	if len(images) > maxAlbumSize {
+		images = images[:maxAlbumSize]
+	}
+	req := b.client.R().SetFormData(map[string]string{"chat_id": strconv.FormatInt(chat, 10)})
+	media := make([]inputMedia, 0, len(images))
+	for i, img := range images {
+		name := "photo" + strconv.Itoa(i)
+		m := inputMedia{Type: "photo", Media: "attach://" + name}
+		if i == 0 {
+			m.Caption, m.ParseMode = caption, parseMode
+		}
+		media = append(media, m)
+		req.SetFileReader(name, name+".jpg", bytes.NewReader(img))
+	}
+	var ids []int64
+	mediaJSON, err := json.Marshal(media)
+	if err == nil {
+		var msgs []botAPIMessage
+		if err = b.call(ctx, "sendMediaGroup", req.SetFormData(map[string]string{"media": string(mediaJSON)}), &msgs); err == nil {
+			ids = make([]int64, 0, len(msgs))
+			for _, m := range msgs {
+				ids = append(ids, m.MessageId)
+			}
+		}
+	}
+	return ids, err
+}
+
+func (b *botAPI) editMessageText(ctx context.Context, chat, id int64, text, parseMode string) error {
+	return b.call(ctx, "editMessageText", b.client.R().SetFormData(map[string]string{
+		"chat_id":    strconv.FormatInt(chat, 10),
+		"message_id": strconv.FormatInt(id, 10),
+		"text":       text,
+		"parse_mode": parseMode,
+	}), nil)
+}
+
+func (b *botAPI) editMessageCaption(ctx context.Context, chat, id int64, caption, parseMode string) error {
+	return b.call(ctx, "editMessageCaption", b.client.R().SetFormData(map[string]string{
+		"chat_id":    strconv.FormatInt(chat, 10),
+		"message_id": strconv.FormatInt(id, 10),
+		"caption":    caption,
+		"parse_mode": parseMode,
+	}), nil)
+}
+
+func (b *botAPI) replyMessage(ctx context.Context, chat, replyTo int64, text, parseMode string) (int64, error) {
+	msg := new(botAPIMessage)
+	err := b.call(ctx, "sendMessage", b.client.R().SetFormData(map[string]string{
+		"chat_id":          strconv.FormatInt(chat, 10),
+		"text":             text,
+		"parse_mode":       parseMode,
+		"reply_parameters": fmt.Sprintf(`{"message_id":%d,"allow_sending_without_reply":true}`, replyTo),
+	}), msg)
+	return msg.MessageId, err
+}
+
+type botAPIUpdate struct {
+	UpdateId    int64 `json:"update_id"`
+	InlineQuery *struct {
+		Id     string `json:"id"`
+		Query  string `json:"query"`
+		Offset string `json:"offset"`
+		From   struct {
+			Id int64 `json:"id"`
+		} `json:"from"`
+	} `json:"inline_query"`
+}
+
+type inlineResult struct {
+	Type                string `json:"type"`
+	Id                  string `json:"id"`
+	Title               string `json:"title"`
+	Description         string `json:"description,omitempty"`
+	URL                 string `json:"url,omitempty"`
+	ThumbnailURL        string `json:"thumbnail_url,omitempty"`
+	InputMessageContent struct {
+		MessageText string `json:"message_text"`
+		ParseMode   string `json:"parse_mode,omitempty"`
+	} `json:"input_message_content"`
+}
+
+// getUpdates long polls inline queries, other updates are handled by client
+func (b *botAPI) getUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]botAPIUpdate, error) {
+	var updates []botAPIUpdate
+	err := b.call(ctx, "getUpdates", b.client.R().SetFormData(map[string]string{
+		"offset":          strconv.FormatInt(offset, 10),
+		"timeout":         strconv.Itoa(int(timeout.Seconds())),
+		"allowed_updates": `["inline_query"]`,
+	}), &updates)
+	return updates, err
+}
+
+func (b *botAPI) answerInlineQuery(ctx context.Context, id string, results []inlineResult, nextOffset string, cacheTime uint) error {
+	resultsJSON, err := json.Marshal(results)
+	if err == nil {
+		err = b.call(ctx, "answerInlineQuery", b.client.R().SetFormData(map[string]string{
+			"inline_query_id": id,
+			"results":         string(resultsJSON),
+			"next_offset":     nextOffset,
+			"cache_time":      strconv.FormatUint(uint64(cacheTime), 10),
+		}), nil)
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package tg
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+
+	"sot-te.ch/TTObserverV1/producer"
+	s "sot-te.ch/TTObserverV1/shared"
+)
+
+const (
+	cmdReannounce = "/reannounce"
+	cmdSetOffset  = "/setoffset"
+	cmdPause      = "/pause"
+	cmdResume     = "/resume"
+)
+
+var errNoController = errors.New("observer control not available")
+
+// controller holds observer controller, which is set after notifier is started
+type controller struct {
+	producer.Controller
+	mu sync.RWMutex
+}
+
+func (tg *Notifier) SetController(c producer.Controller) {
+	tg.controller.mu.Lock()
+	defer tg.controller.mu.Unlock()
+	tg.controller.Controller = c
+}
+
+// control calls fn with observer controller if chat is admin and responds with message
+// from chat's locale, selected by resp, on success
+func (tg *Notifier) control(chat int64, cmd string, resp func(*locale) string, fn func(producer.Controller) error) error {
+	isAdmin, err := tg.db.GetAdminExist(chat)
+	if err != nil {
+		logger.Warningf("%s: %v", cmd, err)
+		return err
+	} else if !isAdmin {
+		logger.Infof("%s unauthorized %d", cmd, chat)
+		return tg.unauthorized(chat)
+	}
+	tg.controller.mu.RLock()
+	c := tg.controller.Controller
+	tg.controller.mu.RUnlock()
+	if c == nil {
+		return errNoController
+	}
+	logger.Notice(cmd, " called ", chat)
+	if err = fn(c); err == nil {
+		tg.client.SendMsg(resp(tg.chatLocale(chat)), []int64{chat}, false)
+	}
+	return err
+}
+
+func (tg *Notifier) reannounce(chat int64, args []string) error {
+	return tg.control(chat, cmdReannounce, func(l *locale) string { return l.Reannounced }, func(c producer.Controller) error {
+		if len(args) == 0 {
+			return s.ErrRequiredParameters
+		}
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err == nil {
+			err = c.Reannounce(id, args[1:])
+		}
+		return err
+	})
+}
+
+func (tg *Notifier) setOffset(chat int64, args []string) error {
+	return tg.control(chat, cmdSetOffset, func(l *locale) string { return l.OffsetSet }, func(c producer.Controller) error {
+		if len(args) == 0 {
+			return s.ErrRequiredParameters
+		}
+		offset, err := strconv.ParseUint(args[0], 10, 32)
+		if err == nil {
+			err = c.SetOffset(uint(offset))
+		}
+		return err
+	})
+}
+
+func (tg *Notifier) pause(chat int64, paused bool) error {
+	if paused {
+		return tg.control(chat, cmdPause, func(l *locale) string { return l.Paused }, func(c producer.Controller) error {
+			c.Pause()
+			return nil
+		})
+	}
+	return tg.control(chat, cmdResume, func(l *locale) string { return l.Resumed }, func(c producer.Controller) error {
+		c.Resume()
+		return nil
+	})
+}
@@ -0,0 +1,130 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package tg
+
+import (
+	"slices"
+	"strings"
+
+	s "sot-te.ch/TTObserverV1/shared"
+)
+
+const (
+	cmdFollow    = "/follow"
+	cmdUnfollow  = "/unfollow"
+	cmdFollowing = "/following"
+
+	metaSeparator = ":"
+)
+
+// normalizeQuery lower-cases query and collapses spaces, so the same query is stored once
+func normalizeQuery(args []string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.Join(args, " ")), " "))
+}
+
+// matchQuery reports whether every term of query is found in release name or meta values,
+// term `field:value` is searched only in meta field. Query is expected to be normalized
+func matchQuery(query string, torrent *s.TorrentInfo) bool {
+	for _, term := range strings.Fields(query) {
+		if field, value, ok := strings.Cut(term, metaSeparator); ok && len(field) > 0 && len(value) > 0 {
+			if !metaContains(torrent.Meta, field, value) {
+				return false
+			}
+		} else if !strings.Contains(strings.ToLower(torrent.Name), term) && !metaContains(torrent.Meta, "", term) {
+			return false
+		}
+	}
+	return true
+}
+
+// metaContains reports whether value of meta field (or any field if field is empty) contains str
+func metaContains(meta map[string]string, field, str string) bool {
+	for k, v := range meta {
+		if (len(field) == 0 || strings.ToLower(k) == field) && strings.Contains(strings.ToLower(v), str) {
+			return true
+		}
+	}
+	return false
+}
+
+// followers returns subscribed chats, which should get release: chats without
+// subscriptions get everything, others - only releases matching any of subscriptions.
+// If torrent is nil, all subscribed chats are returned
+func (tg *Notifier) followers(torrent *s.TorrentInfo) ([]int64, error) {
+	chats, err := tg.db.GetChats()
+	if err != nil || torrent == nil {
+		return chats, err
+	}
+	subs, err := tg.db.GetSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(chats, func(chat int64) bool {
+		queries, ok := subs[chat]
+		return ok && !slices.ContainsFunc(queries, func(query string) bool {
+			return matchQuery(query, torrent)
+		})
+	}), nil
+}
+
+func (tg *Notifier) follow(chat int64, args []string) error {
+	query := normalizeQuery(args)
+	if len(query) == 0 {
+		return s.ErrRequiredParameters
+	}
+	err := tg.db.AddChat(chat)
+	if err == nil {
+		err = tg.db.AddChatSubscription(chat, query)
+	}
+	if err == nil {
+		logger.Info("Chat ", chat, " follows ", query)
+		tg.client.SendMsg(tg.chatLocale(chat).Followed, []int64{chat}, false)
+	}
+	return err
+}
+
+func (tg *Notifier) unfollow(chat int64, args []string) error {
+	query := normalizeQuery(args)
+	err := tg.db.DelChatSubscription(chat, query)
+	if err == nil {
+		logger.Info("Chat ", chat, " unfollows ", query)
+		tg.client.SendMsg(tg.chatLocale(chat).Unfollowed, []int64{chat}, false)
+	}
+	return err
+}
+
+func (tg *Notifier) following(chat int64) error {
+	queries, err := tg.db.GetChatSubscriptions(chat)
+	if err == nil {
+		resp := tg.chatLocale(chat).FollowAll
+		if len(queries) > 0 {
+			resp = strings.Join(queries, "\n")
+		}
+		tg.client.SendMsg(resp, []int64{chat}, false)
+	}
+	return err
+}
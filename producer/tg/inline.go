@@ -0,0 +1,155 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package tg
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"sot-te.ch/TTObserverV1/producer"
+	s "sot-te.ch/TTObserverV1/shared"
+)
+
+const (
+	inlinePollTimeout = 30 * time.Second
+	inlineRetryDelay  = 5 * time.Second
+	inlineLimit       = 20
+	// maxInlineLimit is count of results allowed by Bot API
+	maxInlineLimit = 50
+	minInlineQuery = 2
+)
+
+// pollInline receives inline queries through Bot API until ctx cancelled
+func (tg *Notifier) pollInline(ctx context.Context) {
+	logger.Info("Starting inline queries polling")
+	var offset int64
+	for ctx.Err() == nil {
+		updates, err := tg.botAPI.getUpdates(ctx, offset, inlinePollTimeout)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Warning("Unable to get inline queries: ", err)
+				select {
+				case <-time.After(inlineRetryDelay):
+				case <-ctx.Done():
+				}
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateId + 1
+			if q := u.InlineQuery; q != nil {
+				if err = tg.answerInline(ctx, q.Id, q.Query, q.Offset, q.From.Id); err != nil {
+					logger.Warning("Unable to answer inline query: ", err)
+				}
+			}
+		}
+	}
+}
+
+// answerInline sends page of releases found by name or meta, offset is page number.
+// Results are formatted in locale of user's private chat
+func (tg *Notifier) answerInline(ctx context.Context, id, query, offset string, user int64) error {
+	query = strings.TrimSpace(query)
+	if len([]rune(query)) < minInlineQuery {
+		return tg.botAPI.answerInlineQuery(ctx, id, []inlineResult{}, "", tg.Inline.CacheTime)
+	}
+	limit := tg.Inline.Limit
+	if limit == 0 {
+		limit = inlineLimit
+	} else if limit > maxInlineLimit {
+		limit = maxInlineLimit
+	}
+	page, _ := strconv.ParseUint(offset, 10, 32)
+	torrents, err := tg.db.FindTorrents(query, uint(page)*limit, limit+1)
+	if err != nil {
+		return err
+	}
+	var next string
+	if uint(len(torrents)) > limit {
+		torrents, next = torrents[:limit], strconv.FormatUint(page+1, 10)
+	}
+	l := tg.chatLocale(user)
+	results := make([]inlineResult, 0, len(torrents))
+	for _, t := range torrents {
+		var torrent *s.TorrentInfo
+		if torrent, err = s.ParseTorrent(t.Data); err != nil {
+			logger.Warning("Unable to parse release ", t.Id, ": ", err)
+			continue
+		}
+		for f := range torrent.Files {
+			torrent.Files[f] = false
+		}
+		torrent.Id = t.Id
+		if len(t.URL) > 0 {
+			torrent.URL = t.URL
+		}
+		if torrent.Meta, err = tg.db.GetTorrentMeta(t.Id); err != nil {
+			return err
+		}
+		res := inlineResult{
+			Type:        "article",
+			Id:          strconv.FormatInt(t.Id, 10),
+			Title:       tg.replaceName(torrent.Name),
+			Description: producer.FormatFileSize(torrent.Length),
+			URL:         torrent.URL,
+		}
+		if len(tg.Inline.ThumbMeta) > 0 {
+			// multiple values are separated, first one is used
+			poster, _, _ := strings.Cut(torrent.Meta[tg.Inline.ThumbMeta], s.MetaValueSeparator)
+			res.ThumbnailURL = thumbnailURL(poster, torrent.URL)
+		}
+		var msg string
+		if msg, err = producer.FormatMessage(l.templates.inline, tg.announceData(l, l.Added, torrent)); err != nil {
+			return err
+		}
+		res.InputMessageContent.MessageText, res.InputMessageContent.ParseMode = tg.format(msg)
+		results = append(results, res)
+	}
+	return tg.botAPI.answerInlineQuery(ctx, id, results, next, tg.Inline.CacheTime)
+}
+
+// thumbnailURL resolves poster URL, which may be relative, against release URL,
+// returns empty string if result is not absolute http URL
+func thumbnailURL(poster, release string) string {
+	if len(poster) == 0 {
+		return ""
+	}
+	ref, err := url.Parse(poster)
+	if err != nil {
+		return ""
+	}
+	if base, baseErr := url.Parse(release); baseErr == nil {
+		ref = base.ResolveReference(ref)
+	}
+	if (ref.Scheme != "http" && ref.Scheme != "https") || len(ref.Host) == 0 {
+		return ""
+	}
+	return ref.String()
+}
@@ -0,0 +1,50 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package tg
+
+import "testing"
+
+func TestThumbnailURL(t *testing.T) {
+	tests := []struct {
+		name, poster, release, want string
+	}{
+		{"absolute", "https://img.host/p.jpg", "https://tt.host/r/1", "https://img.host/p.jpg"},
+		{"root relative", "/images/p.jpg", "https://tt.host/r/1", "https://tt.host/images/p.jpg"},
+		{"relative", "p.jpg", "https://tt.host/r/1", "https://tt.host/r/p.jpg"},
+		{"scheme relative", "//img.host/p.jpg", "http://tt.host/r/1", "http://img.host/p.jpg"},
+		{"no release url", "/images/p.jpg", "", ""},
+		{"not http", "ftp://img.host/p.jpg", "https://tt.host/r/1", ""},
+		{"empty", "", "https://tt.host/r/1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := thumbnailURL(tt.poster, tt.release); got != tt.want {
+				t.Errorf("thumbnailURL(%q, %q) = %q, want %q", tt.poster, tt.release, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package tg
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	tmpl "text/template"
+
+	mt "sot-te.ch/GoMTHelper"
+
+	s "sot-te.ch/TTObserverV1/shared"
+)
+
+const (
+	cmdLang = "/lang"
+
+	defaultLocale = "default"
+	currentMark   = "* "
+)
+
+var errUnknownLocale = errors.New("unknown locale")
+
+// messageSet is set of messages, which may be localized in msg.locales
+type messageSet struct {
+	mt.TGMessages
+	State           string `json:"state"`
+	Announce        string `json:"announce,omitempty"`
+	Nx              string `json:"n1x,omitempty"`
+	Added           string `json:"added,omitempty"`
+	Updated         string `json:"updated,omitempty"`
+	UpdateReply     string `json:"updatereply,omitempty"`
+	Inline          string `json:"inline,omitempty"`
+	Followed        string `json:"followed,omitempty"`
+	Unfollowed      string `json:"unfollowed,omitempty"`
+	FollowAll       string `json:"followall,omitempty"`
+	Reannounced     string `json:"reannounced,omitempty"`
+	OffsetSet       string `json:"offsetset,omitempty"`
+	Paused          string `json:"paused,omitempty"`
+	Resumed         string `json:"resumed,omitempty"`
+	LangSet         string `json:"langset,omitempty"`
+	SingleIndex     string `json:"singleindex,omitempty"`
+	MultipleIndexes string `json:"multipleindexes,omitempty"`
+}
+
+type messageTemplates struct {
+	state           *tmpl.Template
+	announce        *tmpl.Template
+	updateReply     *tmpl.Template
+	inline          *tmpl.Template
+	nx              *tmpl.Template
+	singleIndex     *tmpl.Template
+	multipleIndexes *tmpl.Template
+}
+
+// locale is message set with compiled templates
+type locale struct {
+	messageSet
+	templates *messageTemplates
+}
+
+// compileLocale parses templates of message set, prefix is config path of set
+func compileLocale(set messageSet, prefix string) (*locale, error) {
+	var errs []error
+	parse := func(name, field, text string) *tmpl.Template {
+		t, err := tmpl.New(name).Parse(text)
+		errs = append(errs, s.FieldError("", prefix+field, err))
+		return t
+	}
+	l := &locale{
+		messageSet: set,
+		templates: &messageTemplates{
+			announce:        parse("announce", "announce", set.Announce),
+			updateReply:     parse("updateReply", "updatereply", set.UpdateReply),
+			inline:          parse("inline", "inline", set.Inline),
+			state:           parse("state", "state", set.State),
+			nx:              parse("n1000", "n1x", set.Nx),
+			singleIndex:     parse("singleIndex", "singleindex", set.SingleIndex),
+			multipleIndexes: parse("multipleIndexes", "multipleindexes", set.MultipleIndexes),
+		},
+	}
+	if len(set.Inline) == 0 {
+		l.templates.inline = l.templates.announce
+	}
+	return l, errors.Join(errs...)
+}
+
+// compileLocales compiles default message set and sets from msg.locales,
+// fields not set in locale are taken from default set
+func (tg *Notifier) compileLocales() error {
+	def, err := compileLocale(tg.Messages.messageSet, "msg.")
+	errs := []error{err}
+	tg.locales = map[string]*locale{"": def, tg.defaultLocale(): def}
+	for name, raw := range tg.Messages.Locales {
+		prefix := "msg.locales." + name
+		set := tg.Messages.messageSet
+		if err = json.Unmarshal(raw, &set); err != nil {
+			errs = append(errs, s.FieldError("", prefix, err))
+			continue
+		}
+		var l *locale
+		l, err = compileLocale(set, prefix+".")
+		errs = append(errs, err)
+		tg.locales[name] = l
+	}
+	return errors.Join(errs...)
+}
+
+func (tg *Notifier) defaultLocale() string {
+	if len(tg.Messages.Locale) > 0 {
+		return tg.Messages.Locale
+	}
+	return defaultLocale
+}
+
+// locale returns message set by name, default set if not found
+func (tg *Notifier) locale(name string) *locale {
+	if l, ok := tg.locales[name]; ok {
+		return l
+	}
+	return tg.locales[""]
+}
+
+// chatLocale returns message set chosen by chat
+func (tg *Notifier) chatLocale(chat int64) *locale {
+	name, err := tg.db.GetChatLocale(chat)
+	if err != nil {
+		logger.Warning("Unable to get locale of ", chat, ": ", err)
+	}
+	return tg.locale(name)
+}
+
+// unauthorized returns error with auth message of chat's locale
+func (tg *Notifier) unauthorized(chat int64) error {
+	return errors.New(tg.chatLocale(chat).Unauthorized)
+}
+
+// useLocale sets messages of chat's locale to mt client before it replies to chat:
+// built-in commands call backend functions and other commands are wrapped with localized
+func (tg *Notifier) useLocale(chat int64) {
+	l := tg.chatLocale(chat)
+	tg.clientLocale.Lock()
+	tg.client.Messages = l.TGMessages
+	tg.clientLocale.Unlock()
+}
+
+// localized wraps command handler to reply errors in chat's locale
+func (tg *Notifier) localized(handler func(int64, []string) error) func(int64, string, []string) error {
+	return func(chat int64, _ string, args []string) error {
+		tg.useLocale(chat)
+		return handler(chat, args)
+	}
+}
+
+// setLang sets chat's locale, without arguments lists available locales
+func (tg *Notifier) setLang(chat int64, args []string) error {
+	if len(args) == 0 {
+		current, err := tg.db.GetChatLocale(chat)
+		if err != nil {
+			return err
+		}
+		if _, ok := tg.Messages.Locales[current]; !ok {
+			current = tg.defaultLocale()
+		}
+		names := []string{tg.defaultLocale()}
+		for name := range tg.Messages.Locales {
+			names = append(names, name)
+		}
+		slices.Sort(names[1:])
+		for i, name := range names {
+			if name == current {
+				names[i] = currentMark + name
+			}
+		}
+		tg.client.SendMsg(strings.Join(names, "\n"), []int64{chat}, false)
+		return nil
+	}
+	name := args[0]
+	if name == tg.defaultLocale() {
+		name = ""
+	} else if _, ok := tg.Messages.Locales[name]; !ok {
+		return errUnknownLocale
+	}
+	err := tg.db.SetChatLocale(chat, name)
+	if err == nil {
+		logger.Info("Chat ", chat, " locale set to ", args[0])
+		tg.client.SendMsg(tg.locale(name).LangSet, []int64{chat}, false)
+	}
+	return err
+}
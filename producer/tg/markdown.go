@@ -0,0 +1,104 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package tg
+
+import (
+	"html"
+	"strings"
+)
+
+const (
+	parseModeMarkdown = "markdown"
+	parseModeText     = "text"
+	parseModeHTML     = "HTML"
+)
+
+// markdownTags are markdown entities, used by telegram clients (and TDLib formatted messages), and their HTML tags
+var markdownTags = []struct {
+	marker, tag string
+	raw         bool
+}{
+	{"```", "pre", true},
+	{"`", "code", true},
+	{"**", "b", false},
+	{"__", "i", false},
+	{"~~", "s", false},
+	{"||", "tg-spoiler", false},
+}
+
+// markdownToHTML converts client markdown (**bold**, __italic__, ~~strike~~, ||spoiler||,
+// `code`, ```pre```, [text](url)) to Bot API HTML, unpaired markers are kept as is
+func markdownToHTML(text string) string {
+	sb := strings.Builder{}
+	for len(text) > 0 {
+		converted := false
+		for _, t := range markdownTags {
+			if !strings.HasPrefix(text, t.marker) {
+				continue
+			}
+			end := strings.Index(text[len(t.marker):], t.marker)
+			if end <= 0 {
+				continue
+			}
+			inner := text[len(t.marker) : len(t.marker)+end]
+			if t.raw {
+				inner = html.EscapeString(inner)
+			} else {
+				inner = markdownToHTML(inner)
+			}
+			sb.WriteString("<" + t.tag + ">" + inner + "</" + t.tag + ">")
+			text, converted = text[2*len(t.marker)+end:], true
+			break
+		}
+		if !converted && text[0] == '[' {
+			if mid := strings.Index(text, "]("); mid > 0 {
+				if end := strings.IndexByte(text[mid:], ')'); end > 2 {
+					sb.WriteString(`<a href="` + html.EscapeString(text[mid+2:mid+end]) + `">` +
+						markdownToHTML(text[1:mid]) + "</a>")
+					text, converted = text[mid+end+1:], true
+				}
+			}
+		}
+		if !converted {
+			sb.WriteString(html.EscapeString(text[:1]))
+			text = text[1:]
+		}
+	}
+	return sb.String()
+}
+
+// format converts message to parse mode set in config: client markdown (default) is converted
+// to HTML, parseModeText is sent without entities, Bot API modes are sent as is
+func (tg *Notifier) format(text string) (string, string) {
+	switch tg.Messages.ParseMode {
+	case "", parseModeMarkdown:
+		return markdownToHTML(text), parseModeHTML
+	case parseModeText:
+		return text, ""
+	}
+	return text, tg.Messages.ParseMode
+}
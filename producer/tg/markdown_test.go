@@ -0,0 +1,54 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package tg
+
+import "testing"
+
+func TestMarkdownToHTML(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", "a < b & c", "a &lt; b &amp; c"},
+		{"bold", "**Torrent added**", "<b>Torrent added</b>"},
+		{"code", "Name: `a<b>`", "Name: <code>a&lt;b&gt;</code>"},
+		{"pre", "```x `y` z```", "<pre>x `y` z</pre>"},
+		{"nested", "**bold __italic__**", "<b>bold <i>italic</i></b>"},
+		{"link", "[Download📥](https://t.co/a?b=1&c=2)", `<a href="https://t.co/a?b=1&amp;c=2">Download📥</a>`},
+		{"link in bold", "**[x](u)**", `<b><a href="u">x</a></b>`},
+		{"unpaired", "2**3 and __init", "2**3 and __init"},
+		{"empty marker", "****", "****"},
+		{"not a link", "[x] (y)", "[x] (y)"},
+		{"spoiler and strike", "||s|| ~~d~~", "<tg-spoiler>s</tg-spoiler> <s>d</s>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markdownToHTML(tt.in); got != tt.want {
+				t.Errorf("markdownToHTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
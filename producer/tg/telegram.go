@@ -27,15 +27,13 @@
 package tg
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
-	tmpl "text/template"
+	"sync"
 
 	"github.com/op/go-logging"
 	mt "sot-te.ch/GoMTHelper"
@@ -48,50 +46,75 @@ const (
 	msgWatch = "watch"
 	msgAdmin = "admin"
 
+	releasesPageSize = 20
+	pagePrefix       = "#"
+
+	torrentFileWith      = "with"
+	torrentFileInstead   = "instead"
+	torrentFileExt       = ".torrent"
+	invalidFileNameChars = `/\:*?"<>|`
+
 	cmdLsAdmins     = "/lsadmins"
 	cmdLsChats      = "/lschats"
+	cmdLsReleases   = "/lsreleases"
 	cmdUpdatePoster = "/uploadposter"
 )
 
 var (
-	logger      = logging.MustGetLogger("tg")
-	errNotFound = errors.New("not found")
+	logger             = logging.MustGetLogger("tg")
+	errNotFound        = errors.New("not found")
+	errTorrentFileMode = errors.New("must be one of: " + torrentFileWith + ", " + torrentFileInstead)
 )
 
 func init() {
-	producer.RegisterFactory("telegram", new(Notifier))
+	producer.RegisterFactoryV2("telegram", new(Notifier))
 }
 
-type messageTemplates struct {
-	state           *tmpl.Template
-	announce        *tmpl.Template
-	nx              *tmpl.Template
-	singleIndex     *tmpl.Template
-	multipleIndexes *tmpl.Template
+// document is a file attached to announce
+type document struct {
+	name string
+	data []byte
 }
 
 type Notifier struct {
 	ApiId     int32  `json:"apiid"`
 	ApiHash   string `json:"apihash"`
 	BotToken  string `json:"bottoken"`
+	BotAPIURL string `json:"botapiurl"`
 	DBPath    string `json:"dbpath"`
 	FileStore string `json:"filestorepath"`
 	OTPSeed   string `json:"otpseed,omitempty"`
-	Messages  struct {
-		mt.TGMessages
-		State           string            `json:"state"`
-		Announce        string            `json:"announce,omitempty"`
-		Nx              string            `json:"n1x,omitempty"`
-		Replacements    map[string]string `json:"replacements"`
-		Added           string            `json:"added,omitempty"`
-		Updated         string            `json:"updated,omitempty"`
-		SingleIndex     string            `json:"singleindex,omitempty"`
-		MultipleIndexes string            `json:"multipleindexes,omitempty"`
+	// UpdateMode is one of producer.UpdateNew, producer.UpdateEdit, producer.UpdateEditReply
+	UpdateMode string `json:"updatemode,omitempty"`
+	// TorrentFile is one of torrentFileWith, torrentFileInstead or empty if torrent file is not attached
+	TorrentFile string `json:"torrentfile,omitempty"`
+	// TorrentFileLimit is maximal size (in bytes) of attached torrent file
+	TorrentFileLimit uint `json:"torrentfilelimit,omitempty"`
+	Inline           struct {
+		Enabled bool `json:"enabled"`
+		// Limit is count of results per page
+		Limit uint `json:"limit,omitempty"`
+		// ThumbMeta is meta field with poster URL
+		ThumbMeta string `json:"thumbmeta,omitempty"`
+		CacheTime uint   `json:"cachetime,omitempty"`
+	} `json:"inline"`
+	Messages struct {
+		messageSet
+		Replacements map[string]string `json:"replacements"`
+		ParseMode    string            `json:"parsemode,omitempty"`
+		// Locale is name of default message set
+		Locale string `json:"locale,omitempty"`
+		// Locales are message sets chosen by chats, which override default set
+		Locales map[string]json.RawMessage `json:"locales,omitempty"`
 	} `json:"msg"`
-	messages        *messageTemplates
-	db              s.Database
-	client          *mt.Telegram
-	errUnauthorized error
+	locales    map[string]*locale
+	db         s.Database
+	client     *mt.Telegram
+	botAPI     *botAPI
+	controller controller
+	stopInline context.CancelFunc
+	// clientLocale guards messages of mt client, which replies to built-in commands
+	clientLocale sync.Mutex
 }
 
 func (tg *Notifier) getChats(chat int64, admins bool) error {
@@ -123,7 +146,7 @@ func (tg *Notifier) getChats(chat int64, admins bool) error {
 	} else {
 		if err == nil {
 			logger.Infof("LsChats unauthorized %d", chat)
-			err = tg.errUnauthorized
+			err = tg.unauthorized(chat)
 		} else {
 			logger.Warningf("LsChats: %v", err)
 		}
@@ -134,6 +157,50 @@ func (tg *Notifier) getChats(chat int64, admins bool) error {
 	return err
 }
 
+// lsReleases sends page of releases, which names match sql like query.
+// Page number (starting from 1) may be set by last argument prefixed with #
+func (tg *Notifier) lsReleases(chat int64, args []string) error {
+	var err error
+	var isAdmin bool
+	if isAdmin, err = tg.db.GetAdminExist(chat); isAdmin {
+		page := uint64(1)
+		if l := len(args); l > 1 && strings.HasPrefix(args[l-1], pagePrefix) {
+			if page, err = strconv.ParseUint(strings.TrimPrefix(args[l-1], pagePrefix), 10, 32); err != nil || page == 0 {
+				return s.ErrRequiredParameters
+			}
+			args = args[:l-1]
+		}
+		query := strings.Join(args, " ")
+		if len(query) == 0 {
+			return s.ErrRequiredParameters
+		}
+		logger.Notice("LsReleases called", chat, query)
+		var torrents []s.DBTorrent
+		if torrents, err = tg.db.SearchTorrents(query, uint(page-1)*releasesPageSize, releasesPageSize+1); err == nil {
+			if len(torrents) == 0 {
+				return errNotFound
+			}
+			sb := strings.Builder{}
+			for i, t := range torrents {
+				if i == releasesPageSize {
+					sb.WriteString(fmt.Sprintf("\n%s %s %s%d", cmdLsReleases, query, pagePrefix, page+1))
+					break
+				}
+				sb.WriteString(fmt.Sprintf("%d: %s\n", t.Id, t.Name))
+			}
+			tg.client.SendMsg(sb.String(), []int64{chat}, false)
+		} else {
+			logger.Warningf("LsReleases: %v", err)
+		}
+	} else if err == nil {
+		logger.Infof("LsReleases unauthorized %d", chat)
+		err = tg.unauthorized(chat)
+	} else {
+		logger.Warningf("LsReleases: %v", err)
+	}
+	return err
+}
+
 func (tg *Notifier) getState(chat int64) (string, error) {
 	var err error
 	var isMob, isAdmin bool
@@ -147,7 +214,7 @@ func (tg *Notifier) getState(chat int64) (string, error) {
 	if index, err = tg.db.GetCrawlOffset(); err != nil {
 		return "", err
 	}
-	return producer.FormatMessage(tg.messages.state, map[string]any{
+	return producer.FormatMessage(tg.chatLocale(chat).templates.state, map[string]any{
 		msgWatch:          isMob,
 		msgAdmin:          isAdmin,
 		producer.MsgIndex: index,
@@ -168,7 +235,7 @@ func (tg *Notifier) uploadPoster(chat int64, args []string) error {
 					var torrentPoster []byte
 					if torrentPoster, err = s.GetTorrentPoster(args[1], 0); err == nil {
 						if err = tg.db.AddTorrentImage(torrentId, torrentPoster); err == nil {
-							tg.client.SendMsg(tg.Messages.Added, []int64{chat}, false)
+							tg.client.SendMsg(tg.chatLocale(chat).Added, []int64{chat}, false)
 						}
 					}
 				} else {
@@ -179,7 +246,7 @@ func (tg *Notifier) uploadPoster(chat int64, args []string) error {
 	} else {
 		if err == nil {
 			logger.Infof("UploadPoster unauthorized %d", chat)
-			err = tg.errUnauthorized
+			err = tg.unauthorized(chat)
 		} else {
 			logger.Warningf("UploadPoster: %v", err)
 		}
@@ -187,155 +254,470 @@ func (tg *Notifier) uploadPoster(chat int64, args []string) error {
 	return err
 }
 
+func (tg *Notifier) compileTemplates() error {
+	errs := []error{tg.compileLocales()}
+	errs = append(errs, s.FieldError("", "updatemode", producer.CheckUpdateMode(tg.UpdateMode)))
+	if tg.TorrentFile != "" && tg.TorrentFile != torrentFileWith && tg.TorrentFile != torrentFileInstead {
+		errs = append(errs, s.FieldError("", "torrentfile", errTorrentFileMode))
+	}
+	return errors.Join(errs...)
+}
+
 func (tg *Notifier) init() error {
 	var err error
+	if err = tg.compileTemplates(); err != nil {
+		return err
+	}
 	tg.client = mt.New(tg.ApiId, tg.ApiHash, tg.DBPath, tg.FileStore, tg.OTPSeed)
-	tg.messages = new(messageTemplates)
+	tg.botAPI = newBotAPI(tg.BotAPIURL, tg.BotToken)
 	tg.client.Messages = tg.Messages.TGMessages
 	tg.client.BackendFunctions = mt.TGBackendFunction{
-		ChatExist:  tg.db.GetChatExist,
-		ChatAdd:    tg.db.AddChat,
-		ChatRm:     tg.db.DelChat,
-		AdminExist: tg.db.GetAdminExist,
-		AdminAdd:   tg.db.AddAdmin,
-		AdminRm:    tg.db.DelAdmin,
-		State:      tg.getState,
+		ChatExist: func(chat int64) (bool, error) {
+			tg.useLocale(chat)
+			return tg.db.GetChatExist(chat)
+		},
+		ChatAdd: func(chat int64) error {
+			tg.useLocale(chat)
+			return tg.db.AddChat(chat)
+		},
+		ChatRm: func(chat int64) error {
+			tg.useLocale(chat)
+			return tg.db.DelChat(chat)
+		},
+		AdminExist: func(chat int64) (bool, error) {
+			tg.useLocale(chat)
+			return tg.db.GetAdminExist(chat)
+		},
+		AdminAdd: func(chat int64) error {
+			tg.useLocale(chat)
+			return tg.db.AddAdmin(chat)
+		},
+		AdminRm: func(chat int64) error {
+			tg.useLocale(chat)
+			return tg.db.DelAdmin(chat)
+		},
+		State: func(chat int64) (string, error) {
+			tg.useLocale(chat)
+			return tg.getState(chat)
+		},
 	}
 	tg.client.SetLogger(logger)
 	if err = tg.client.LoginAsBot(tg.BotToken, mt.MtLogWarning); err == nil {
 		var subErr error
-		if subErr = tg.client.AddCommand(cmdLsChats, func(chat int64, _ string, _ []string) error {
+		if subErr = tg.client.AddCommand(cmdLsChats, tg.localized(func(chat int64, _ []string) error {
 			return tg.getChats(chat, false)
-		}); subErr != nil {
+		})); subErr != nil {
 			logger.Error(subErr)
 		}
-		if subErr = tg.client.AddCommand(cmdLsAdmins, func(chat int64, _ string, _ []string) error {
+		if subErr = tg.client.AddCommand(cmdLsAdmins, tg.localized(func(chat int64, _ []string) error {
 			return tg.getChats(chat, true)
-		}); subErr != nil {
+		})); subErr != nil {
+			logger.Error(subErr)
+		}
+		if subErr = tg.client.AddCommand(cmdLsReleases, tg.localized(func(chat int64, args []string) error {
+			return tg.lsReleases(chat, args)
+		})); subErr != nil {
 			logger.Error(subErr)
 		}
-		if subErr = tg.client.AddCommand(cmdUpdatePoster, func(chat int64, _ string, args []string) error {
+		if subErr = tg.client.AddCommand(cmdUpdatePoster, tg.localized(func(chat int64, args []string) error {
 			return tg.uploadPoster(chat, args)
-		}); subErr != nil {
+		})); subErr != nil {
+			logger.Error(subErr)
+		}
+		if subErr = tg.client.AddCommand(cmdFollow, tg.localized(func(chat int64, args []string) error {
+			return tg.follow(chat, args)
+		})); subErr != nil {
+			logger.Error(subErr)
+		}
+		if subErr = tg.client.AddCommand(cmdUnfollow, tg.localized(func(chat int64, args []string) error {
+			return tg.unfollow(chat, args)
+		})); subErr != nil {
+			logger.Error(subErr)
+		}
+		if subErr = tg.client.AddCommand(cmdFollowing, tg.localized(func(chat int64, _ []string) error {
+			return tg.following(chat)
+		})); subErr != nil {
 			logger.Error(subErr)
 		}
-		if tg.messages.announce, subErr = tmpl.New("announce").Parse(tg.Messages.Announce); subErr != nil {
+		if subErr = tg.client.AddCommand(cmdReannounce, tg.localized(func(chat int64, args []string) error {
+			return tg.reannounce(chat, args)
+		})); subErr != nil {
 			logger.Error(subErr)
 		}
-		if tg.messages.state, subErr = tmpl.New("state").Parse(tg.Messages.State); subErr != nil {
+		if subErr = tg.client.AddCommand(cmdSetOffset, tg.localized(func(chat int64, args []string) error {
+			return tg.setOffset(chat, args)
+		})); subErr != nil {
 			logger.Error(subErr)
 		}
-		if tg.messages.nx, subErr = tmpl.New("n1000").Parse(tg.Messages.Nx); subErr != nil {
+		if subErr = tg.client.AddCommand(cmdPause, tg.localized(func(chat int64, _ []string) error {
+			return tg.pause(chat, true)
+		})); subErr != nil {
 			logger.Error(subErr)
 		}
-		if tg.messages.singleIndex, subErr = tmpl.New("singleIndex").Parse(tg.Messages.SingleIndex); subErr != nil {
+		if subErr = tg.client.AddCommand(cmdResume, tg.localized(func(chat int64, _ []string) error {
+			return tg.pause(chat, false)
+		})); subErr != nil {
 			logger.Error(subErr)
 		}
-		if tg.messages.multipleIndexes, subErr = tmpl.New("multipleIndexes").Parse(tg.Messages.MultipleIndexes); subErr != nil {
+		if subErr = tg.client.AddCommand(cmdLang, tg.localized(func(chat int64, args []string) error {
+			return tg.setLang(chat, args)
+		})); subErr != nil {
 			logger.Error(subErr)
 		}
-		tg.errUnauthorized = errors.New(tg.Messages.Unauthorized)
 	}
 	return err
 }
 
-func (tg *Notifier) sendMsgToMobs(msg string, photo []byte) {
-	var chats []int64
+// sendToChat sends message with images and torrent file to chat, if message is too long
+// to be a caption, it is sent separately after images
+func (tg *Notifier) sendToChat(ctx context.Context, chat int64, msg string, images [][]byte, doc *document) ([]int64, error) {
 	var err error
-	if chats, err = tg.db.GetChats(); err != nil {
-		logger.Error(err)
+	var ids []int64
+	text, parseMode := tg.format(msg)
+	caption := text
+	if len([]rune(msg)) > maxCaptionLength || len(images) == 0 && doc == nil {
+		caption = ""
 	}
-	photoParams := mt.MediaParams{}
-	if len(photo) > 0 {
-		ext := "*."
-		if exts := strings.Split(http.DetectContentType(photo), "/"); len(exts) > 1 {
-			ext += exts[1]
-		}
-		var tmpFile *os.File
-		if tmpFile, err = os.CreateTemp("", ext); err == nil {
-			if _, err = tmpFile.Write(photo); err == nil {
-				_ = tmpFile.Sync()
-				photoParams.Path = tmpFile.Name()
-			}
-			if err = tmpFile.Close(); err != nil {
-				logger.Error(err)
+	switch len(images) {
+	case 0:
+	case 1:
+		var id int64
+		if id, err = tg.botAPI.sendPhoto(ctx, chat, images[0], caption, parseMode); err == nil {
+			ids = append(ids, id)
+		}
+	default:
+		ids, err = tg.botAPI.sendMediaGroup(ctx, chat, images, caption, parseMode)
+	}
+	if err == nil && doc != nil {
+		// document gets caption only if there are no images
+		docCaption := caption
+		if len(images) > 0 {
+			docCaption = ""
+		}
+		var id int64
+		if id, err = tg.botAPI.sendDocument(ctx, chat, doc.name, doc.data, docCaption, parseMode); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if err == nil && len(caption) == 0 {
+		var id int64
+		if id, err = tg.botAPI.sendMessage(ctx, chat, text, parseMode); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, err
+}
+
+// editInChat edits announce previously sent to chat as messages with ids
+// and replies to it if configured. If message can't be edited, announce is sent anew
+func (tg *Notifier) editInChat(ctx context.Context, chat int64, ids []int64, msg, reply string, images [][]byte, doc *document) ([]int64, error) {
+	text, parseMode := tg.format(msg)
+	edits := []func() error{
+		func() error {
+			return tg.botAPI.editMessageText(ctx, chat, ids[len(ids)-1], text, parseMode)
+		},
+		func() error {
+			return tg.botAPI.editMessageCaption(ctx, chat, ids[0], text, parseMode)
+		},
+	}
+	if (len(images) > 0 || doc != nil) && len([]rune(msg)) <= maxCaptionLength {
+		// announce was probably sent as caption
+		edits[0], edits[1] = edits[1], edits[0]
+	}
+	var err error
+	for _, edit := range edits {
+		var apiErr *botAPIError
+		if err = edit(); err == nil || errors.As(err, &apiErr) && apiErr.notModified() {
+			if len(reply) > 0 {
+				replyText, replyMode := tg.format(reply)
+				if _, err = tg.botAPI.replyMessage(ctx, chat, ids[0], replyText, replyMode); err != nil {
+					logger.Warning("Unable to reply to announce in ", chat, ": ", err)
+				}
 			}
+			return ids, nil
+		} else if apiErr == nil || apiErr.temporary() {
+			return nil, err
 		}
 	}
-	tg.client.SendPhoto(photoParams, msg, chats, true)
-	if len(photoParams.Path) > 0 {
-		if err = os.Remove(photoParams.Path); err != nil {
-			logger.Error(err)
+	logger.Info("Unable to edit announce in ", chat, ": ", err, ", sending new one")
+	return tg.sendToChat(ctx, chat, msg, images, doc)
+}
+
+// sendToMobs calls send with chat's locale for every subscribed chat, which follows torrent
+// (or every chat if torrent is nil)
+func (tg *Notifier) sendToMobs(ctx context.Context, torrent *s.TorrentInfo, send func(chat int64, l *locale) ([]int64, error)) ([]producer.Receipt, error) {
+	chats, err := tg.followers(torrent)
+	var locales map[int64]string
+	if err == nil {
+		locales, err = tg.db.GetChatLocales()
+	}
+	if err != nil {
+		return nil, &producer.DeliveryError{Temporary: true, Err: err}
+	}
+	var receipts []producer.Receipt
+	var errs []error
+	for _, chat := range chats {
+		if err = ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		target := strconv.FormatInt(chat, 10)
+		ids, err := send(chat, tg.locale(locales[chat]))
+		for _, id := range ids {
+			receipts = append(receipts, producer.Receipt{Target: target, MessageId: strconv.FormatInt(id, 10)})
+		}
+		if err != nil {
+			de := &producer.DeliveryError{Target: target, Err: err}
+			var apiErr *botAPIError
+			if errors.As(err, &apiErr) {
+				de.Temporary, de.RetryAfter = apiErr.temporary(), apiErr.retryAfter
+			} else {
+				de.Temporary = producer.IsTemporary(err)
+			}
+			errs = append(errs, de)
 		}
 	}
+	return receipts, errors.Join(errs...)
 }
 
-func (*Notifier) New(configPath string, db s.Database) (producer.Producer, error) {
+func (*Notifier) New(config []byte, db s.Database) (producer.ProducerV2, error) {
 	var err error
 	n := &Notifier{db: db}
-	var confBytes []byte
-	if confBytes, err = os.ReadFile(filepath.Clean(configPath)); err == nil {
-		if err = json.Unmarshal(confBytes, n); err == nil {
-			if err = n.init(); err == nil {
-				go n.client.HandleUpdates()
+	if err = s.DecodeConfig(config, n); err == nil {
+		s.AddSecret(n.BotToken)
+		s.AddSecret(n.ApiHash)
+		s.AddSecret(n.OTPSeed)
+		if err = n.init(); err == nil {
+			go n.client.HandleUpdates()
+			if n.Inline.Enabled {
+				var ctx context.Context
+				ctx, n.stopInline = context.WithCancel(context.Background())
+				go n.pollInline(ctx)
 			}
 		}
 	}
 	return n, err
 }
 
-func (tg *Notifier) Send(isNew bool, torrent *s.TorrentInfo) {
-	if tg.Messages.Announce == "" {
-		logger.Warning("Announce message not set")
+func (*Notifier) Validate(config []byte, db s.Database) error {
+	n := &Notifier{db: db}
+	if err := s.DecodeConfig(config, n); err != nil {
+		return err
+	}
+	var errs []error
+	if n.ApiId == 0 || len(n.ApiHash) == 0 {
+		errs = append(errs, s.FieldError("", "apiid, apihash", s.ErrRequiredParameters))
+	}
+	if len(n.BotToken) == 0 {
+		errs = append(errs, s.FieldError("", "bottoken", s.ErrRequiredParameters))
 	} else {
-		action := tg.Messages.Updated
-		if isNew {
-			action = tg.Messages.Added
-		}
-		logger.Debugf("Announcing %s for %s", action, torrent.Name)
-		name := torrent.Name
-		if len(tg.Messages.Replacements) > 0 {
-			for k, v := range tg.Messages.Replacements {
-				name = strings.Replace(name, k, v, -1)
+		api := newBotAPI(n.BotAPIURL, n.BotToken)
+		errs = append(errs, s.FieldError("", "bottoken", api.call(context.Background(), "getMe", api.client.R(), nil)))
+	}
+	errs = append(errs, n.compileTemplates())
+	return errors.Join(errs...)
+}
+
+// replaceName applies msg.replacements to release name
+func (tg *Notifier) replaceName(name string) string {
+	for k, v := range tg.Messages.Replacements {
+		name = strings.Replace(name, k, v, -1)
+	}
+	return name
+}
+
+// announceData returns placeholders of announce template in locale
+func (tg *Notifier) announceData(l *locale, action string, torrent *s.TorrentInfo) map[string]any {
+	newIndexes, err := producer.FormatIndexesMessage(producer.GetNewFilesIndexes(torrent.Files),
+		l.templates.singleIndex,
+		l.templates.multipleIndexes, producer.MsgNewIndexes)
+	if err != nil {
+		logger.Error(err)
+	}
+	return map[string]any{
+		producer.MsgAction:     action,
+		producer.MsgName:       tg.replaceName(torrent.Name),
+		producer.MsgSize:       producer.FormatFileSize(torrent.Length),
+		producer.MsgUrl:        torrent.URL,
+		producer.MsgFileCount:  len(torrent.Files),
+		producer.MsgMeta:       torrent.Meta,
+		producer.MsgNewIndexes: newIndexes,
+	}
+}
+
+// announcement is release announce formatted in locale
+type announcement struct {
+	msg, reply string
+	images     [][]byte
+	doc        *document
+}
+
+// announce formats announce message and reply to updated announce (if configured) in locale
+func (tg *Notifier) announce(isNew bool, torrent *s.TorrentInfo, l *locale) (*announcement, error) {
+	if l.Announce == "" {
+		return nil, nil
+	}
+	action := l.Updated
+	if isNew {
+		action = l.Added
+	}
+	logger.Debugf("Announcing %s for %s", action, torrent.Name)
+	data := tg.announceData(l, action, torrent)
+	var err error
+	a := new(announcement)
+	if a.msg, err = producer.FormatMessage(l.templates.announce, data); err != nil {
+		return nil, &producer.DeliveryError{Err: err}
+	}
+	if !isNew && tg.UpdateMode == producer.UpdateEditReply {
+		if a.reply = action; len(l.UpdateReply) > 0 {
+			if a.reply, err = producer.FormatMessage(l.templates.updateReply, data); err != nil {
+				return nil, &producer.DeliveryError{Err: err}
 			}
 		}
-		newIndexes, err := producer.FormatIndexesMessage(producer.GetNewFilesIndexes(torrent.Files),
-			tg.messages.singleIndex,
-			tg.messages.multipleIndexes, producer.MsgNewIndexes)
-		if err != nil {
-			logger.Error(err)
-		}
-		if msg, err := producer.FormatMessage(tg.messages.announce, map[string]any{
-			producer.MsgAction:     action,
-			producer.MsgName:       name,
-			producer.MsgSize:       producer.FormatFileSize(torrent.Length),
-			producer.MsgUrl:        torrent.URL,
-			producer.MsgFileCount:  len(torrent.Files),
-			producer.MsgMeta:       torrent.Meta,
-			producer.MsgNewIndexes: newIndexes,
-		}); err == nil {
-			tg.sendMsgToMobs(msg, torrent.Image)
-		} else {
-			logger.Error(err)
+	}
+	a.images = make([][]byte, 0, len(torrent.Images)+1)
+	if len(torrent.Image) > 0 {
+		a.images = append(a.images, torrent.Image)
+	}
+	a.images = append(a.images, torrent.Images...)
+	if a.doc = tg.torrentFile(torrent); a.doc != nil && tg.TorrentFile == torrentFileInstead {
+		a.images = nil
+	}
+	return a, nil
+}
+
+// announcements returns function, which formats announce in locale once per locale,
+// announce is nil if it is not set in locale.
+// Announce in default locale is formatted at once to report template errors before sending
+func (tg *Notifier) announcements(isNew bool, torrent *s.TorrentInfo) (func(*locale) (*announcement, error), error) {
+	cache := make(map[*locale]*announcement)
+	announce := func(l *locale) (*announcement, error) {
+		if a, ok := cache[l]; ok {
+			return a, nil
+		}
+		a, err := tg.announce(isNew, torrent, l)
+		if err == nil {
+			cache[l] = a
+		}
+		return a, err
+	}
+	a, err := announce(tg.locale(""))
+	if err == nil && a == nil {
+		logger.Warning("Announce message not set")
+	}
+	return announce, err
+}
+
+// torrentFile returns torrent file of release to be attached, nil if disabled or file is too large
+func (tg *Notifier) torrentFile(torrent *s.TorrentInfo) *document {
+	if len(tg.TorrentFile) == 0 || len(torrent.Data) == 0 {
+		return nil
+	}
+	limit := tg.TorrentFileLimit
+	if limit == 0 || limit > maxDocumentSize {
+		limit = maxDocumentSize
+	}
+	if uint(len(torrent.Data)) > limit {
+		logger.Info("Torrent file of ", torrent.Name, " is too large to attach: ", len(torrent.Data))
+		return nil
+	}
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(invalidFileNameChars, r) {
+			return '_'
+		}
+		return r
+	}, torrent.Name)
+	return &document{name: name + torrentFileExt, data: torrent.Data}
+}
+
+func (tg *Notifier) Send(ctx context.Context, isNew bool, torrent *s.TorrentInfo) ([]producer.Receipt, error) {
+	announce, err := tg.announcements(isNew, torrent)
+	if err != nil {
+		return nil, err
+	}
+	return tg.sendToMobs(ctx, torrent, func(chat int64, l *locale) ([]int64, error) {
+		a, err := announce(l)
+		if err != nil || a == nil {
+			return nil, err
+		}
+		return tg.sendToChat(ctx, chat, a.msg, a.images, a.doc)
+	})
+}
+
+// Update edits announces in chats, which already got release
+func (tg *Notifier) Update(ctx context.Context, torrent *s.TorrentInfo, previous []producer.Receipt) ([]producer.Receipt, error) {
+	announce, err := tg.announcements(false, torrent)
+	if err != nil {
+		return nil, err
+	}
+	sent := make(map[int64][]int64, len(previous))
+	for _, r := range previous {
+		chat, chatErr := strconv.ParseInt(r.Target, 10, 64)
+		id, idErr := strconv.ParseInt(r.MessageId, 10, 64)
+		if chatErr == nil && idErr == nil {
+			sent[chat] = append(sent[chat], id)
 		}
 	}
+	return tg.sendToMobs(ctx, torrent, func(chat int64, l *locale) ([]int64, error) {
+		a, err := announce(l)
+		if err != nil || a == nil {
+			return nil, err
+		}
+		if ids := sent[chat]; len(ids) > 0 {
+			return tg.editInChat(ctx, chat, ids, a.msg, a.reply, a.images, a.doc)
+		}
+		return tg.sendToChat(ctx, chat, a.msg, a.images, a.doc)
+	})
 }
 
-func (tg *Notifier) SendNxGet(offset uint) {
-	if len(tg.Messages.Nx) == 0 {
+func (tg *Notifier) SendNxGet(ctx context.Context, offset uint) error {
+	if len(tg.Messages.Nx) == 0 && len(tg.Messages.Locales) == 0 {
 		logger.Warning("Nx message not set")
-	} else {
-		logger.Debugf("Notifying %d GET", offset)
-		if msg, err := producer.FormatMessage(tg.messages.nx, map[string]any{
-			producer.MsgIndex: offset,
-		}); err == nil {
-			tg.sendMsgToMobs(msg, nil)
-		} else {
-			logger.Error(err)
+		return nil
+	}
+	logger.Debugf("Notifying %d GET", offset)
+	data := map[string]any{
+		producer.MsgIndex: offset,
+	}
+	_, err := tg.sendToMobs(ctx, nil, func(chat int64, l *locale) ([]int64, error) {
+		if len(l.Nx) == 0 {
+			return nil, nil
 		}
+		msg, err := producer.FormatMessage(l.templates.nx, data)
+		if err != nil {
+			return nil, err
+		}
+		return tg.sendToChat(ctx, chat, msg, nil, nil)
+	})
+	return err
+}
+
+// SendBatch sends digest message to every subscribed chat
+func (tg *Notifier) SendBatch(ctx context.Context, msg string) ([]producer.Receipt, error) {
+	return tg.sendToMobs(ctx, nil, func(chat int64, _ *locale) ([]int64, error) {
+		return tg.sendToChat(ctx, chat, msg, nil, nil)
+	})
+}
+
+func (tg *Notifier) Capabilities() producer.Capability {
+	if tg.UpdateMode == producer.UpdateEdit || tg.UpdateMode == producer.UpdateEditReply {
+		return producer.CapMedia | producer.CapBatch | producer.CapEdit
 	}
+	return producer.CapMedia | producer.CapBatch
 }
 
-func (tg *Notifier) Close() {
+func (tg *Notifier) NotifyAdmins(msg string) {
+	if admins, err := tg.db.GetAdmins(); err == nil {
+		tg.client.SendMsg(msg, admins, false)
+	} else {
+		logger.Error(err)
+	}
+}
+
+func (tg *Notifier) Close() error {
+	if tg.stopInline != nil {
+		tg.stopInline()
+	}
 	tg.client.Close()
+	return nil
 }
@@ -28,14 +28,14 @@ package vk
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
-	"os"
-	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	tmpl "text/template"
 	"time"
@@ -49,18 +49,20 @@ import (
 )
 
 const (
-	msgTags = "tags"
+	msgTags        = "tags"
+	maxAttachments = 10
 )
 
 var (
 	logger                     = logging.MustGetLogger("vk")
+	errAnnounceNotSet          = errors.New("announce message not set")
 	nonLetterNumberSpaceRegexp = regexp.MustCompile(`(?m)[^\p{L}\p{N}_\s]`)
 	isEmptyRegexp              = regexp.MustCompile("^$")
 	allSpacesRegexp            = regexp.MustCompile(`(?m)\s`)
 )
 
 func init() {
-	producer.RegisterFactory("vkcom", Notifier{})
+	producer.RegisterFactoryV2("vkcom", Notifier{})
 }
 
 type Notifier struct {
@@ -69,7 +71,9 @@ type Notifier struct {
 	GroupIds        []uint `json:"groupids"`
 	IgnoreUnchanged bool   `json:"ignoreunchanged"`
 	IgnoreRegexp    string `json:"ignoreregexp"`
-	Messages        *struct {
+	// UpdateMode is one of producer.UpdateNew, producer.UpdateEdit, producer.UpdateEditReply
+	UpdateMode string `json:"updatemode"`
+	Messages   *struct {
 		Announce            string `json:"announce"`
 		announceTmpl        *tmpl.Template
 		Nx                  string `json:"n1x"`
@@ -77,7 +81,9 @@ type Notifier struct {
 		Replacements        map[string]string `json:"replacements"`
 		Added               string            `json:"added"`
 		Updated             string            `json:"updated"`
-		SingleIndex         string            `json:"singleindex"`
+		UpdateReply         string            `json:"updatereply"`
+		updateReplyTmpl     *tmpl.Template
+		SingleIndex         string `json:"singleindex"`
 		singleIndexTmpl     *tmpl.Template
 		MultipleIndexes     string `json:"multipleindexes"`
 		multipleIndexesTmpl *tmpl.Template
@@ -90,56 +96,88 @@ type Notifier struct {
 	db            s.Database
 }
 
-func (Notifier) New(configPath string, db s.Database) (producer.Producer, error) {
+func (Notifier) New(config []byte, db s.Database) (producer.ProducerV2, error) {
 	var err error
 	n := &Notifier{db: db}
-	var confBytes []byte
-	if confBytes, err = os.ReadFile(filepath.Clean(configPath)); err == nil {
-		if err = json.Unmarshal(confBytes, n); err == nil {
-			if len(n.Token) > 0 {
-				if len(n.IgnoreRegexp) == 0 {
-					n.ignorePattern = isEmptyRegexp // is empty
-				} else {
-					n.ignorePattern, err = regexp.Compile(n.IgnoreRegexp)
-				}
-				if err == nil {
-					var subErr error
-					c := resty.New().
-						SetBaseURL("https://api.vk.ru/method").
-						SetFormData(map[string]string{
-							"access_token": n.Token,
-							"lang":         "ru",
-							"v":            "5.101",
-						}).
-						SetTimeout(15 * time.Second)
-					if len(n.Proxy) > 0 {
-						c.SetProxy(n.Proxy)
-					}
-					n.client = &vkapi.API{
-						Token:  n.Token,
-						Client: c,
-					}
-					if n.Messages.announceTmpl, subErr = tmpl.New("announce").Parse(n.Messages.Announce); subErr != nil {
-						logger.Error(subErr)
-					}
-					if n.Messages.nxTmpl, subErr = tmpl.New("n1000").Parse(n.Messages.Nx); subErr != nil {
-						logger.Error(subErr)
-					}
-					if n.Messages.singleIndexTmpl, subErr = tmpl.New("singleIndex").Parse(n.Messages.SingleIndex); subErr != nil {
-						logger.Error(subErr)
-					}
-					if n.Messages.multipleIndexesTmpl, subErr = tmpl.New("multipleIndexes").Parse(n.Messages.MultipleIndexes); subErr != nil {
-						logger.Error(subErr)
-					}
-				}
-			} else {
-				err = s.ErrRequiredParameters
+	if err = s.DecodeConfig(config, n); err == nil {
+		s.AddSecret(n.Token)
+		if len(n.Token) > 0 {
+			if err = n.compile(); err == nil {
+				n.client = n.newClient()
 			}
+		} else {
+			err = s.ErrRequiredParameters
 		}
 	}
 	return n, err
 }
 
+func (Notifier) Validate(config []byte, db s.Database) error {
+	n := &Notifier{db: db}
+	if err := s.DecodeConfig(config, n); err != nil {
+		return err
+	}
+	var errs []error
+	if len(n.Token) == 0 {
+		errs = append(errs, s.FieldError("", "token", s.ErrRequiredParameters))
+	} else {
+		client := n.newClient()
+		for _, id := range n.GroupIds {
+			// upload server is requested on every announce, it checks token scopes and group access
+			_, err := client.PhotosGetWallUploadServer(vkapi.PhotosGetWallUploadServerParams{GroupID: int(id)})
+			errs = append(errs, s.FieldError("", fmt.Sprint("groupids: ", id), err))
+		}
+	}
+	if len(n.GroupIds) == 0 {
+		errs = append(errs, s.FieldError("", "groupids", s.ErrRequiredParameters))
+	}
+	errs = append(errs, n.compile())
+	return errors.Join(errs...)
+}
+
+func (vk *Notifier) compile() error {
+	var err error
+	var errs []error
+	if len(vk.IgnoreRegexp) == 0 {
+		vk.ignorePattern = isEmptyRegexp // is empty
+	} else if vk.ignorePattern, err = regexp.Compile(vk.IgnoreRegexp); err != nil {
+		errs = append(errs, s.FieldError("", "ignoreregexp", err))
+	}
+	if vk.Messages == nil {
+		return errors.Join(append(errs, s.FieldError("", "msg", s.ErrRequiredParameters))...)
+	}
+	parse := func(name, field, text string) *tmpl.Template {
+		t, err := tmpl.New(name).Parse(text)
+		errs = append(errs, s.FieldError("", field, err))
+		return t
+	}
+	vk.Messages.announceTmpl = parse("announce", "msg.announce", vk.Messages.Announce)
+	vk.Messages.updateReplyTmpl = parse("updateReply", "msg.updatereply", vk.Messages.UpdateReply)
+	vk.Messages.nxTmpl = parse("n1000", "msg.n1x", vk.Messages.Nx)
+	vk.Messages.singleIndexTmpl = parse("singleIndex", "msg.singleindex", vk.Messages.SingleIndex)
+	vk.Messages.multipleIndexesTmpl = parse("multipleIndexes", "msg.multipleindexes", vk.Messages.MultipleIndexes)
+	errs = append(errs, s.FieldError("", "updatemode", producer.CheckUpdateMode(vk.UpdateMode)))
+	return errors.Join(errs...)
+}
+
+func (vk Notifier) newClient() *vkapi.API {
+	c := resty.New().
+		SetBaseURL("https://api.vk.ru/method").
+		SetFormData(map[string]string{
+			"access_token": vk.Token,
+			"lang":         "ru",
+			"v":            "5.101",
+		}).
+		SetTimeout(15 * time.Second)
+	if len(vk.Proxy) > 0 {
+		c.SetProxy(vk.Proxy)
+	}
+	return &vkapi.API{
+		Token:  vk.Token,
+		Client: c,
+	}
+}
+
 func (vk Notifier) uploadImage(photo []byte, groupId uint) (string, error) {
 	var err error
 	var photoAttachment string
@@ -207,92 +245,213 @@ func (vk Notifier) buildHashTags(meta map[string]string) string {
 	return tags.String()
 }
 
-func (vk Notifier) Send(isNew bool, torrent *s.TorrentInfo) {
-	var err error
-	if len(vk.Messages.Announce) > 0 {
-		changedIndexes := producer.GetNewFilesIndexes(torrent.Files)
-		if (vk.IgnoreUnchanged && len(changedIndexes) > 0 || !vk.IgnoreUnchanged) && !vk.ignorePattern.MatchString(torrent.Name) {
-			if vk.client != nil {
-				for _, groupId := range vk.GroupIds {
-					var photoAttachment string
-					if len(torrent.Image) > 0 {
-						photoAttachment, err = vk.uploadImage(torrent.Image, groupId)
-					}
-					if err != nil {
-						logger.Error(err)
-					}
-					action := vk.Messages.Updated
-					if isNew {
-						action = vk.Messages.Added
-					}
-					logger.Debugf("Announcing %s for %s", action, torrent.Name)
-					name := torrent.Name
-					if len(vk.Messages.Replacements) > 0 {
-						for k, v := range vk.Messages.Replacements {
-							name = strings.Replace(name, k, v, -1)
-						}
-					}
-					newIndexes, err := producer.FormatIndexesMessage(changedIndexes, vk.Messages.singleIndexTmpl,
-						vk.Messages.multipleIndexesTmpl, producer.MsgNewIndexes)
-					if err != nil {
-						logger.Error(err)
-					}
-					if msg, err := producer.FormatMessage(vk.Messages.announceTmpl, map[string]any{
-						producer.MsgAction:     action,
-						producer.MsgName:       name,
-						producer.MsgSize:       producer.FormatFileSize(torrent.Length),
-						producer.MsgUrl:        torrent.URL,
-						producer.MsgFileCount:  len(torrent.Files),
-						producer.MsgMeta:       torrent.Meta,
-						producer.MsgNewIndexes: newIndexes,
-						msgTags:                vk.buildHashTags(torrent.Meta),
-					}); err == nil {
-						params := vkapi.WallPostParams{
-							OwnerID:     -int(groupId),
-							FromGroup:   true,
-							Message:     msg,
-							Attachments: photoAttachment,
-						}
-						var wallResp *vkapi.WallPostResp
-						if wallResp, err = vk.client.WallPost(params); err == nil {
-							logger.Debugf("New post ID %d", wallResp.PostID)
-						}
-					}
-
-					if err != nil {
-						logger.Error(err)
-					}
-				}
+// announce formats announce message and reply to updated announce (if configured),
+// skip is true if release is ignored
+func (vk Notifier) announce(isNew bool, torrent *s.TorrentInfo) (msg, reply string, images [][]byte, skip bool, err error) {
+	if len(vk.Messages.Announce) == 0 {
+		err = &producer.DeliveryError{Err: errAnnounceNotSet}
+		return
+	}
+	changedIndexes := producer.GetNewFilesIndexes(torrent.Files)
+	if vk.IgnoreUnchanged && len(changedIndexes) == 0 || vk.ignorePattern.MatchString(torrent.Name) {
+		logger.Debug("Ignoring ", torrent.Name)
+		skip = true
+		return
+	}
+	action := vk.Messages.Updated
+	if isNew {
+		action = vk.Messages.Added
+	}
+	logger.Debugf("Announcing %s for %s", action, torrent.Name)
+	name := torrent.Name
+	if len(vk.Messages.Replacements) > 0 {
+		for k, v := range vk.Messages.Replacements {
+			name = strings.Replace(name, k, v, -1)
+		}
+	}
+	newIndexes, err := producer.FormatIndexesMessage(changedIndexes, vk.Messages.singleIndexTmpl,
+		vk.Messages.multipleIndexesTmpl, producer.MsgNewIndexes)
+	if err != nil {
+		logger.Error(err)
+	}
+	data := map[string]any{
+		producer.MsgAction:     action,
+		producer.MsgName:       name,
+		producer.MsgSize:       producer.FormatFileSize(torrent.Length),
+		producer.MsgUrl:        torrent.URL,
+		producer.MsgFileCount:  len(torrent.Files),
+		producer.MsgMeta:       torrent.Meta,
+		producer.MsgNewIndexes: newIndexes,
+		msgTags:                vk.buildHashTags(torrent.Meta),
+	}
+	if msg, err = producer.FormatMessage(vk.Messages.announceTmpl, data); err != nil {
+		err = &producer.DeliveryError{Err: err}
+		return
+	}
+	if !isNew && vk.UpdateMode == producer.UpdateEditReply {
+		if reply = action; len(vk.Messages.UpdateReply) > 0 {
+			if reply, err = producer.FormatMessage(vk.Messages.updateReplyTmpl, data); err != nil {
+				err = &producer.DeliveryError{Err: err}
+				return
 			}
 		}
-	} else {
-		logger.Warning("Announce message not set")
 	}
+	images = make([][]byte, 0, len(torrent.Images)+1)
+	if len(torrent.Image) > 0 {
+		images = append(images, torrent.Image)
+	}
+	images = append(images, torrent.Images...)
+	if len(images) > maxAttachments {
+		images = images[:maxAttachments]
+	}
+	return
 }
 
-func (vk Notifier) SendNxGet(offset uint) {
-	if len(vk.Messages.Nx) > 0 {
-		if vk.client != nil {
-			for _, groupId := range vk.GroupIds {
-				logger.Debugf("Notifying %d GET", offset)
-				if msg, err := producer.FormatMessage(vk.Messages.nxTmpl, map[string]any{
-					producer.MsgIndex: offset,
-				}); err == nil {
-					params := vkapi.WallPostParams{
-						OwnerID:   -int(groupId),
-						FromGroup: true,
-						Message:   msg,
-					}
-					var wallResp *vkapi.WallPostResp
-					if wallResp, err = vk.client.WallPost(params); err == nil {
-						logger.Debugf("New post ID %d", wallResp.PostID)
-					}
-				} else {
-					logger.Error(err)
-				}
-			}
+func (vk Notifier) Send(ctx context.Context, isNew bool, torrent *s.TorrentInfo) ([]producer.Receipt, error) {
+	msg, _, images, skip, err := vk.announce(isNew, torrent)
+	if skip || err != nil {
+		return nil, err
+	}
+	return vk.post(ctx, func(groupId uint) (int, error) {
+		return vk.wallPost(groupId, msg, images)
+	})
+}
+
+// Update edits posts in groups, which already got release
+func (vk Notifier) Update(ctx context.Context, torrent *s.TorrentInfo, previous []producer.Receipt) ([]producer.Receipt, error) {
+	msg, reply, images, skip, err := vk.announce(false, torrent)
+	if skip || err != nil {
+		return nil, err
+	}
+	posts := make(map[string]int, len(previous))
+	for _, r := range previous {
+		if id, idErr := strconv.Atoi(r.MessageId); idErr == nil {
+			posts[r.Target] = id
+		}
+	}
+	return vk.post(ctx, func(groupId uint) (int, error) {
+		if postId, exist := posts[strconv.FormatUint(uint64(groupId), 10)]; exist {
+			return vk.wallEdit(groupId, postId, msg, reply, images)
+		}
+		return vk.wallPost(groupId, msg, images)
+	})
+}
+
+func (vk Notifier) attachments(groupId uint, images [][]byte) string {
+	attachments := make([]string, 0, len(images))
+	for _, image := range images {
+		if photoAttachment, err := vk.uploadImage(image, groupId); err == nil {
+			attachments = append(attachments, photoAttachment)
+		} else {
+			logger.Error(err)
+		}
+	}
+	return strings.Join(attachments, ",")
+}
+
+func (vk Notifier) wallPost(groupId uint, msg string, images [][]byte) (int, error) {
+	wallResp, err := vk.client.WallPost(vkapi.WallPostParams{
+		OwnerID:     -int(groupId),
+		FromGroup:   true,
+		Message:     msg,
+		Attachments: vk.attachments(groupId, images),
+	})
+	if err != nil {
+		return 0, err
+	}
+	logger.Debugf("New post ID %d", wallResp.PostID)
+	return wallResp.PostID, nil
+}
+
+// wallEdit edits previously published post and comments it if configured.
+// If post can't be edited (i.e. deleted), release is posted anew
+func (vk Notifier) wallEdit(groupId uint, postId int, msg, reply string, images [][]byte) (int, error) {
+	_, err := vk.client.WallEdit(vkapi.WallEditParams{
+		OwnerID:     -int(groupId),
+		PostID:      uint(postId),
+		Message:     msg,
+		Attachments: vk.attachments(groupId, images),
+	})
+	if err != nil {
+		if temporary(err) {
+			return 0, err
+		}
+		logger.Info("Unable to edit post ", postId, " in ", groupId, ": ", err, ", posting new one")
+		return vk.wallPost(groupId, msg, images)
+	}
+	logger.Debugf("Edited post ID %d", postId)
+	if len(reply) > 0 {
+		if _, err = vk.client.WallCreateComment(vkapi.WallCreateCommentParams{
+			OwnerID:   -int(groupId),
+			PostID:    uint(postId),
+			FromGroup: groupId,
+			Message:   reply,
+		}); err != nil {
+			logger.Warning("Unable to comment post ", postId, " in ", groupId, ": ", err)
 		}
 	}
+	return postId, nil
+}
+
+// post calls publish for every group
+func (vk Notifier) post(ctx context.Context, publish func(groupId uint) (int, error)) ([]producer.Receipt, error) {
+	var receipts []producer.Receipt
+	var errs []error
+	for _, groupId := range vk.GroupIds {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		target := strconv.FormatUint(uint64(groupId), 10)
+		if postId, err := publish(groupId); err == nil {
+			receipts = append(receipts, producer.Receipt{Target: target, MessageId: strconv.Itoa(postId)})
+		} else {
+			errs = append(errs, &producer.DeliveryError{Target: target, Temporary: temporary(err), Err: err})
+		}
+	}
+	return receipts, errors.Join(errs...)
+}
+
+// temporary returns true for network errors and vk errors, which may be gone on retry:
+// unknown error, too many requests, flood control, internal server error
+func temporary(err error) bool {
+	var code int
+	if _, scanErr := fmt.Sscanf(err.Error(), "Error code: %d;", &code); scanErr == nil {
+		return code == 1 || code == 6 || code == 9 || code == 10
+	}
+	return true
+}
+
+func (vk Notifier) SendNxGet(ctx context.Context, offset uint) error {
+	if len(vk.Messages.Nx) == 0 {
+		return nil
+	}
+	logger.Debugf("Notifying %d GET", offset)
+	msg, err := producer.FormatMessage(vk.Messages.nxTmpl, map[string]any{
+		producer.MsgIndex: offset,
+	})
+	if err == nil {
+		_, err = vk.post(ctx, func(groupId uint) (int, error) {
+			return vk.wallPost(groupId, msg, nil)
+		})
+	}
+	return err
 }
 
-func (Notifier) Close() {}
+// SendBatch posts digest message to every group
+func (vk Notifier) SendBatch(ctx context.Context, msg string) ([]producer.Receipt, error) {
+	return vk.post(ctx, func(groupId uint) (int, error) {
+		return vk.wallPost(groupId, msg, nil)
+	})
+}
+
+func (vk Notifier) Capabilities() producer.Capability {
+	if vk.UpdateMode == producer.UpdateEdit || vk.UpdateMode == producer.UpdateEditReply {
+		return producer.CapMedia | producer.CapBatch | producer.CapEdit
+	}
+	return producer.CapMedia | producer.CapBatch
+}
+
+func (Notifier) Close() error {
+	return nil
+}
@@ -0,0 +1,399 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	fileRefPrefix   = "file:"
+	secretMask      = "******"
+	minSecretLength = 4
+)
+
+var configRefRegexp = regexp.MustCompile(`\$\$\{[^}]*\}|\$\{[A-Za-z_][A-Za-z0-9_]*(:-[^}]*)?\}|\$\{file:[^}]+\}`)
+
+// verbatimFields hold crawler's own ${name} placeholders and regexp references,
+// references are not expanded in them
+var verbatimFields = []string{"metaactions", "metatransforms"}
+
+var secrets struct {
+	sync.RWMutex
+	values   []string
+	replacer *strings.Replacer
+}
+
+// ConfigError points to the problem location in a configuration file
+type ConfigError struct {
+	Path  string
+	Field string
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	var b bytes.Buffer
+	if len(e.Path) > 0 {
+		b.WriteString(e.Path)
+		b.WriteString(": ")
+	}
+	if len(e.Field) > 0 {
+		b.WriteString(e.Field)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// FieldError returns ConfigError for field of config file located at path,
+// or nil if err is nil
+func FieldError(path, field string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ConfigError{Path: path, Field: field, Err: err}
+}
+
+// LoadConfig reads config file, expands references (see ExpandConfig)
+// and converts yaml (.yaml, .yml) to json. References in yaml are expanded in parsed values
+func LoadConfig(path string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err == nil {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			data, err = YAMLToJSON(data)
+		default:
+			data, err = ExpandConfig(data)
+		}
+	}
+	if err != nil {
+		return nil, &ConfigError{Path: path, Err: err}
+	}
+	return data, nil
+}
+
+// YAMLToJSON converts yaml document to json, expanding references in scalar values (see ExpandConfig)
+func YAMLToJSON(data []byte) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if err := expandYAML(&root); err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := root.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+func expandYAML(node *yaml.Node) error {
+	var errs []error
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if !slices.Contains(verbatimFields, strings.ToLower(node.Content[i].Value)) {
+				errs = append(errs, expandYAML(node.Content[i+1]))
+			}
+		}
+		return errors.Join(errs...)
+	case yaml.ScalarNode:
+		expanded := configRefRegexp.ReplaceAllStringFunc(node.Value, func(ref string) string {
+			value, err := expandRef(ref)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("line %d, column %d: %w", node.Line, node.Column, err))
+			}
+			return value
+		})
+		if expanded != node.Value {
+			node.Value = expanded
+			// unquoted value is resolved again, so reference may be used as number or bool
+			if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+				node.Tag = ""
+			}
+		}
+	}
+	for _, child := range node.Content {
+		errs = append(errs, expandYAML(child))
+	}
+	return errors.Join(errs...)
+}
+
+// DecodeConfig decodes json config to v, errors contain line and column
+// of syntax error or name of mistyped field
+func DecodeConfig(data []byte, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		ce := &ConfigError{Err: err}
+		if errors.As(err, &syntaxErr) {
+			ce.Field = position(data, syntaxErr.Offset)
+		} else if errors.As(err, &typeErr) {
+			ce.Field, ce.Err = typeErr.Field, fmt.Errorf("%s expected, got %s", typeErr.Type, typeErr.Value)
+			if len(ce.Field) == 0 {
+				ce.Field = position(data, typeErr.Offset)
+			}
+		}
+		return ce
+	}
+	return nil
+}
+
+// ReadConfig reads json or yaml config file to v (see LoadConfig and DecodeConfig)
+func ReadConfig(path string, v any) error {
+	data, err := LoadConfig(path)
+	if err == nil {
+		if err = DecodeConfig(data, v); err != nil {
+			err.(*ConfigError).Path = path
+		}
+	}
+	return err
+}
+
+// ExpandConfig replaces references in config data:
+// ${NAME} and ${NAME:-default} with value of environment variable NAME,
+// ${file:/path} with trimmed content of file (i.e. docker or kubernetes secret),
+// $${...} with literal ${...}. References are not expanded in crawler's metaactions
+// and metatransforms, which have own ${name} placeholders.
+// Substituted values are escaped as json string content, content of files is masked in log (see MaskSecrets).
+func ExpandConfig(data []byte) ([]byte, error) {
+	data, err := expandBare(data)
+	if err != nil {
+		return data, err
+	}
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if dec.Decode(&doc) != nil {
+		// syntax error is reported with position by DecodeConfig
+		return data, nil
+	}
+	changed, err := expandValue(&doc, "")
+	if err != nil || !changed {
+		return data, err
+	}
+	return json.Marshal(doc)
+}
+
+// expandBare replaces references outside of json strings, i.e. used as numbers
+func expandBare(data []byte) ([]byte, error) {
+	var errs []error
+	res := make([]byte, 0, len(data))
+	inString, escaped := false, false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '$':
+			if loc := configRefRegexp.FindIndex(data[i:]); loc != nil && loc[0] == 0 {
+				ref := data[i : i+loc[1]]
+				value, err := expandRef(string(ref))
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", position(data, int64(i)), err))
+					value = string(ref)
+				}
+				res = append(res, escapeJSON(value)...)
+				i += loc[1] - 1
+				continue
+			}
+		}
+		res = append(res, c)
+	}
+	return res, errors.Join(errs...)
+}
+
+// expandValue replaces references in string values of decoded json,
+// returns true if any value is changed
+func expandValue(v *any, field string) (bool, error) {
+	var errs []error
+	var changed bool
+	switch value := (*v).(type) {
+	case map[string]any:
+		for k, e := range value {
+			if slices.Contains(verbatimFields, strings.ToLower(k)) {
+				continue
+			}
+			name := k
+			if len(field) > 0 {
+				name = field + "." + k
+			}
+			c, err := expandValue(&e, name)
+			value[k], changed, errs = e, changed || c, append(errs, err)
+		}
+	case []any:
+		for i := range value {
+			c, err := expandValue(&value[i], fmt.Sprintf("%s[%d]", field, i))
+			changed, errs = changed || c, append(errs, err)
+		}
+	case string:
+		expanded := configRefRegexp.ReplaceAllStringFunc(value, func(ref string) string {
+			res, err := expandRef(ref)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", field, err))
+			}
+			return res
+		})
+		if expanded != value {
+			*v, changed = expanded, true
+		}
+	}
+	return changed, errors.Join(errs...)
+}
+
+// expandRef returns value of reference, or reference itself with error if it can not be resolved
+func expandRef(ref string) (string, error) {
+	if strings.HasPrefix(ref, "$$") {
+		return ref[1:], nil
+	}
+	name := ref[2 : len(ref)-1]
+	if path, isFile := strings.CutPrefix(name, fileRefPrefix); isFile {
+		content, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return ref, err
+		}
+		value := strings.TrimSpace(string(content))
+		AddSecret(value)
+		return value, nil
+	}
+	name, def, hasDef := strings.Cut(name, ":-")
+	value, exist := os.LookupEnv(name)
+	if hasDef && len(value) == 0 {
+		return def, nil
+	} else if !exist {
+		return ref, fmt.Errorf("environment variable %s not set", name)
+	}
+	return value, nil
+}
+
+// AddSecret registers value to be masked by MaskSecrets and masking writer.
+// Values shorter than 4 symbols are ignored to keep log readable.
+func AddSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+	secrets.Lock()
+	defer secrets.Unlock()
+	if slices.Contains(secrets.values, value) {
+		return
+	}
+	secrets.values = append(secrets.values, value)
+	// longest secrets first, so one secret containing another is masked entirely
+	slices.SortFunc(secrets.values, func(a, b string) int {
+		return len(b) - len(a)
+	})
+	pairs := make([]string, 0, len(secrets.values)*2)
+	for _, v := range secrets.values {
+		pairs = append(pairs, v, secretMask)
+	}
+	secrets.replacer = strings.NewReplacer(pairs...)
+}
+
+// AddDSNSecret registers password of data source name (url or key=value form)
+// and whole dsn containing it to be masked
+func AddDSNSecret(dsn string) {
+	var password string
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		password, _ = u.User.Password()
+	} else {
+		for _, f := range strings.Fields(dsn) {
+			if k, v, ok := strings.Cut(f, "="); ok && strings.EqualFold(k, "password") {
+				password = strings.Trim(v, "'")
+			}
+		}
+	}
+	if len(password) > 0 {
+		AddSecret(dsn)
+		AddSecret(password)
+	}
+}
+
+// MaskSecrets replaces all registered secrets in s
+func MaskSecrets(s string) string {
+	secrets.RLock()
+	defer secrets.RUnlock()
+	if secrets.replacer == nil {
+		return s
+	}
+	return secrets.replacer.Replace(s)
+}
+
+type maskingWriter struct {
+	w io.Writer
+}
+
+// NewMaskingWriter returns writer, which masks registered secrets before writing to w
+func NewMaskingWriter(w io.Writer) io.Writer {
+	return maskingWriter{w: w}
+}
+
+func (m maskingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(m.w, MaskSecrets(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func escapeJSON(s string) []byte {
+	b, _ := json.Marshal(s)
+	return b[1 : len(b)-1]
+}
+
+func position(data []byte, offset int64) string {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	line := bytes.Count(data[:offset], []byte{'\n'}) + 1
+	col := offset - int64(bytes.LastIndexByte(data[:offset], '\n'))
+	return fmt.Sprintf("line %d, column %d", line, col)
+}
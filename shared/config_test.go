@@ -0,0 +1,82 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package shared
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExpandConfig(t *testing.T) {
+	t.Setenv("TT_TEST_NAME", `a"b`)
+	t.Setenv("TT_TEST_NUM", "42")
+	tests := []struct {
+		name, in string
+		want     any
+		fails    bool
+	}{
+		{"string", `{"a": "x-${TT_TEST_NAME}"}`, map[string]any{"a": `x-a"b`}, false},
+		{"number", `{"a": ${TT_TEST_NUM}}`, map[string]any{"a": 42.0}, false},
+		{"default", `{"a": "${TT_TEST_UNSET:-d}"}`, map[string]any{"a": "d"}, false},
+		{"escaped", `{"a": "$${TT_TEST_NAME}"}`, map[string]any{"a": "${TT_TEST_NAME}"}, false},
+		{"nested", `{"a": [{"b": "${TT_TEST_NUM}"}]}`, map[string]any{"a": []any{map[string]any{"b": "42"}}}, false},
+		{"verbatim", `{"crawler": {"metaactions": [{"param": "${arg}"}], "metatransforms": {"f": [{"value": "${name}"}]}}}`,
+			map[string]any{"crawler": map[string]any{"metaactions": []any{map[string]any{"param": "${arg}"}},
+				"metatransforms": map[string]any{"f": []any{map[string]any{"value": "${name}"}}}}}, false},
+		{"not set", `{"a": "${TT_TEST_UNSET}"}`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ExpandConfig([]byte(tt.in))
+			if (err != nil) != tt.fails {
+				t.Fatalf("ExpandConfig() error = %v, want error %v", err, tt.fails)
+			}
+			if tt.fails {
+				return
+			}
+			var got any
+			if err = json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandConfig() = %s, want %v", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	t.Setenv("TT_TEST_NUM", "42")
+	in := "a: ${TT_TEST_NUM}\nb: \"${TT_TEST_NUM}\"\nc: $${x}\ncrawler:\n  metaactions:\n    - param: ${arg}\n"
+	want := `{"a":42,"b":"42","c":"${x}","crawler":{"metaactions":[{"param":"${arg}"}]}}`
+	if got, err := YAMLToJSON([]byte(in)); err != nil {
+		t.Fatal(err)
+	} else if string(got) != want {
+		t.Errorf("YAMLToJSON() = %s, want %s", got, want)
+	}
+}
@@ -29,6 +29,7 @@ package shared
 import (
 	"errors"
 	"sync"
+	"time"
 )
 
 const InvalidDBId = -1
@@ -39,6 +40,7 @@ var (
 	dbFactories           = make(map[string]DBFactory)
 	dbFactoriesMu         sync.Mutex
 	ErrRequiredParameters = errors.New("required parameters not set")
+	ErrNotFound           = errors.New("not found")
 )
 
 func RegisterFactory(name string, n DBFactory) {
@@ -55,30 +57,101 @@ func RegisterFactory(name string, n DBFactory) {
 
 type DBTorrent struct {
 	Id          int64
-	Name        string
+	Name, URL   string
 	Data, Image []byte
 }
 
+type PendingTorrent struct {
+	Id      int64
+	Context string
+	IsNew   bool
+	Since   time.Time
+	Retry   time.Time
+	Retries uint
+	Torrent []byte
+}
+
+// OutboxJob is announce of release through one producer, kept until delivered
+type OutboxJob struct {
+	Id       int64
+	Torrent  int64
+	Producer string
+	IsNew    bool
+	Created  time.Time
+	NextTry  time.Time
+	Attempts uint
+	Error    string
+	// Done is time of successful delivery, zero if job is pending
+	Done time.Time
+	// Dead is true if all attempts are exhausted, job is kept until requeued
+	Dead    bool
+	Payload []byte
+}
+
+// DeliveryRecord is entry of delivery ledger: release revision sent (or failed) to target of producer
+type DeliveryRecord struct {
+	Id       int64
+	Torrent  int64
+	Revision time.Time
+	Producer string
+	Target   string
+	// MessageId is id of message in target, empty if failed
+	MessageId string
+	Status    string
+	Error     string
+	Time      time.Time
+}
+
 type Database interface {
 	AddAdmin(id int64) error
 	AddChat(chat int64) error
+	AddChatSubscription(chat int64, query string) error
+	AddDeliveryRecords(records []DeliveryRecord) error
+	AddOutboxJobs(jobs []OutboxJob) error
+	AddPendingTorrent(pending PendingTorrent) error
 	AddTorrentImage(id int64, image []byte) error
+	AddTorrentImages(id int64, images [][]byte) error
 	AddTorrentMeta(id int64, meta map[string]string) error
-	AddTorrent(name string, data []byte, files []string) (int64, error)
+	AddTorrent(name, url string, data []byte, files []string) (int64, error)
+	AddTorrentRevision(id int64, at time.Time) error
 	CheckTorrent(id int64) (bool, error)
 	Close()
 	DelAdmin(id int64) error
 	DelChat(chat int64) error
+	DelChatSubscription(chat int64, query string) error
+	DelPendingTorrent(id int64) error
 	GetAdminExist(chat int64) (bool, error)
 	GetAdmins() ([]int64, error)
 	GetChatExist(chat int64) (bool, error)
+	GetChatLocale(chat int64) (string, error)
+	GetChatLocales() (map[int64]string, error)
 	GetChats() ([]int64, error)
+	GetChatSubscriptions(chat int64) ([]string, error)
+	GetSubscriptions() (map[int64][]string, error)
 	GetCrawlOffset() (uint, error)
+	// GetOutboxJobs returns due jobs in order of creation, jobs created after failed job
+	// of the same producer, which waits for retry, are held back
+	GetOutboxJobs(due time.Time, limit uint) ([]OutboxJob, error)
+	GetDeadOutboxJobs(offset, limit uint) ([]OutboxJob, error)
+	GetDeliveryRecords(torrent int64) ([]DeliveryRecord, error)
+	GetPendingTorrents() ([]PendingTorrent, error)
+	GetScheduledOutboxJobs(after time.Time) ([]OutboxJob, error)
 	GetTorrentFiles(torrent int64) ([]string, error)
 	GetTorrentImage(id int64) ([]byte, error)
+	GetTorrentImages(id int64) ([][]byte, error)
 	GetTorrentMeta(id int64) (map[string]string, error)
+	GetTorrentRevisions(id int64) ([]time.Time, error)
 	GetTorrent(torrent string) (int64, error)
+	GetTorrentById(id int64) (DBTorrent, error)
+	SearchTorrents(query string, offset, limit uint) ([]DBTorrent, error)
+	// FindTorrents searches releases (newest first), which name or meta value contains query, ignoring case
+	FindTorrents(query string, offset, limit uint) ([]DBTorrent, error)
 	UpdateCrawlOffset(offset uint) error
+	UpdateOutboxJob(job OutboxJob) error
+	PurgeOutbox(before time.Time) error
+	SetChatLocale(chat int64, locale string) error
+	RequeueOutboxJob(id int64, next time.Time) error
+	Ping() error
 	MGetTorrents() ([]DBTorrent, error)
 	MPutTorrent(torrent DBTorrent, files []string) error
 }
@@ -0,0 +1,164 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package shared
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	MetricCounter = "counter"
+	MetricGauge   = "gauge"
+	MetricSummary = "summary"
+
+	MetricProbes        = "tt_probes_total"
+	MetricOffset        = "tt_crawl_offset"
+	MetricAnnounced     = "tt_releases_announced_total"
+	MetricHeld          = "tt_releases_held"
+	MetricSendSeconds   = "tt_producer_send_seconds"
+	MetricSendErrors    = "tt_producer_errors_total"
+	MetricCircuitOpen   = "tt_producer_circuit_open"
+	MetricClusterMaster = "tt_cluster_master"
+)
+
+type metric struct {
+	help, kind string
+	values     map[string]float64
+	counts     map[string]uint64
+}
+
+type MetricsRegistry struct {
+	mu      sync.Mutex
+	metrics map[string]*metric
+}
+
+var Metrics = &MetricsRegistry{metrics: make(map[string]*metric)}
+
+func init() {
+	Metrics.Register(MetricProbes, MetricCounter, "Count of checked offsets per outcome")
+	Metrics.Register(MetricOffset, MetricGauge, "Current crawl offset")
+	Metrics.Register(MetricAnnounced, MetricCounter, "Count of announced releases")
+	Metrics.Register(MetricHeld, MetricGauge, "Count of releases held because of missing meta")
+	Metrics.Register(MetricSendSeconds, MetricSummary, "Producer send duration in seconds")
+	Metrics.Register(MetricSendErrors, MetricCounter, "Count of producer send errors")
+	Metrics.Register(MetricCircuitOpen, MetricGauge, "1 if producer is paused by circuit breaker, 0 otherwise")
+	Metrics.Register(MetricClusterMaster, MetricGauge, "1 if node is cluster master, 0 otherwise")
+}
+
+func (r *MetricsRegistry) Register(name, kind, help string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exist := r.metrics[name]; !exist {
+		r.metrics[name] = &metric{
+			help:   help,
+			kind:   kind,
+			values: make(map[string]float64),
+			counts: make(map[string]uint64),
+		}
+	}
+}
+
+func formatLabels(labels []string) string {
+	if len(labels) < 2 {
+		return ""
+	}
+	sb := strings.Builder{}
+	sb.WriteRune('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			sb.WriteRune(',')
+		}
+		sb.WriteString(labels[i])
+		sb.WriteString(`="`)
+		sb.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[i+1]))
+		sb.WriteRune('"')
+	}
+	sb.WriteRune('}')
+	return sb.String()
+}
+
+func (r *MetricsRegistry) update(name string, fn func(m *metric, key string), labels []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m := r.metrics[name]; m != nil {
+		fn(m, formatLabels(labels))
+	}
+}
+
+// Add increments counter or gauge, labels are pairs of name and value
+func (r *MetricsRegistry) Add(name string, v float64, labels ...string) {
+	r.update(name, func(m *metric, key string) {
+		m.values[key] += v
+	}, labels)
+}
+
+func (r *MetricsRegistry) Set(name string, v float64, labels ...string) {
+	r.update(name, func(m *metric, key string) {
+		m.values[key] = v
+	}, labels)
+}
+
+func (r *MetricsRegistry) Observe(name string, v float64, labels ...string) {
+	r.update(name, func(m *metric, key string) {
+		m.values[key] += v
+		m.counts[key]++
+	}, labels)
+}
+
+// WriteTo writes all metrics in prometheus text exposition format
+func (r *MetricsRegistry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sb := strings.Builder{}
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m := r.metrics[name]
+		keys := make([]string, 0, len(m.values))
+		for k := range m.values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sb.WriteString(fmt.Sprintf("# HELP %s %s\n# TYPE %s %s\n", name, m.help, name, m.kind))
+		for _, k := range keys {
+			if m.kind == MetricSummary {
+				sb.WriteString(fmt.Sprintf("%s_sum%s %g\n%s_count%s %d\n", name, k, m.values[k], name, k, m.counts[k]))
+			} else {
+				sb.WriteString(fmt.Sprintf("%s%s %g\n", name, k, m.values[k]))
+			}
+		}
+	}
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
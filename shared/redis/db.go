@@ -27,10 +27,15 @@
 package redis
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"errors"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 
@@ -45,19 +50,32 @@ const (
 	ParamPassword = "password"
 	ParamDB       = "db"
 
-	sChat  = "tt_chat"
-	sAdmin = "tt_adm"
+	sChat    = "tt_chat"
+	sChatSub = "tt_chat_sub_"
+	hLocale  = "tt_chat_locale"
+	sAdmin   = "tt_adm"
 
 	kConfOffset   = "tt_offset"
 	kTorrentIndex = "tt_idx"
 
 	hTorrentId   = "tt_ti"
+	zTorrentName = "tt_tn"
 	hTorrent     = "tt_t_"
 	hTorrentFile = "tt_t_f_"
 	hTorrentMeta = "tt_t_m_"
+	lTorrentImg  = "tt_t_i_"
+	lTorrentRev  = "tt_t_r_"
+	hPending     = "tt_pending"
+	hOutbox      = "tt_outbox"
+	zOutboxDue   = "tt_outbox_due"
+	zOutboxDead  = "tt_outbox_dead"
+	kOutboxId    = "tt_outbox_id"
+	lDelivery    = "tt_t_d_"
+	kDeliveryId  = "tt_delivery_id"
 
 	fIndex = "idx"
 	fName  = "name"
+	fURL   = "url"
 	fData  = "data"
 	fImage = "img"
 )
@@ -84,6 +102,7 @@ func init() {
 			opts := &redis.Options{Addr: v.(string)}
 			if v, ok = m[ParamPassword]; ok && v != nil {
 				opts.Password = v.(string)
+				s.AddSecret(opts.Password)
 			}
 			if v, ok = m[ParamDB]; ok && v != nil {
 				if dbNum, ok := v.(float64); ok {
@@ -93,7 +112,9 @@ func init() {
 				}
 			}
 			db.con = redis.NewClient(opts)
-			err = db.con.Ping(ctx).Err()
+			if err = db.con.Ping(ctx).Err(); err == nil {
+				err = db.indexNames()
+			}
 		} else {
 			err = errors.New("address not set")
 		}
@@ -153,11 +174,11 @@ func (d database) tx(txf func(tx redis.Pipeliner) error) (err error) {
 	return
 }
 
-func (d database) AddTorrent(name string, data []byte, files []string) (int64, error) {
+func (d database) AddTorrent(name, url string, data []byte, files []string) (int64, error) {
 	id := new(int64)
 	err := d.tx(func(tx redis.Pipeliner) (err error) {
 		hkey := hTorrent + name
-		if err = d.con.HSet(ctx, hkey, fName, name, fData, data).Err(); err == nil {
+		if err = d.con.HSet(ctx, hkey, fName, name, fURL, url, fData, data).Err(); err == nil {
 			var sid string
 			if sid, err = d.con.HGet(ctx, hkey, fIndex).Result(); err == nil || asNil(err) == nil {
 				if len(sid) == 0 {
@@ -170,6 +191,9 @@ func (d database) AddTorrent(name string, data []byte, files []string) (int64, e
 				} else {
 					*id, err = strconv.ParseInt(sid, 10, 64)
 				}
+				if err == nil {
+					err = d.con.ZAdd(ctx, zTorrentName, redis.Z{Score: float64(*id), Member: name}).Err()
+				}
 				if err == nil {
 					l := len(files)
 					if l > 0 {
@@ -190,6 +214,26 @@ func (d database) AddTorrent(name string, data []byte, files []string) (int64, e
 	return *id, err
 }
 
+func (d database) AddTorrentRevision(id int64, at time.Time) error {
+	return d.con.RPush(ctx, lTorrentRev+strconv.FormatInt(id, 10), at.Unix()).Err()
+}
+
+func (d database) GetTorrentRevisions(id int64) (out []time.Time, err error) {
+	var stamps []string
+	if stamps, err = d.con.LRange(ctx, lTorrentRev+strconv.FormatInt(id, 10), 0, -1).Result(); err == nil {
+		out = make([]time.Time, 0, len(stamps))
+		for _, st := range stamps {
+			var sec int64
+			if sec, err = strconv.ParseInt(st, 10, 64); err != nil {
+				break
+			}
+			out = append(out, time.Unix(sec, 0))
+		}
+	}
+	err = asNil(err)
+	return
+}
+
 func (d database) CheckTorrent(id int64) (bool, error) {
 	exist, err := d.con.HExists(ctx, hTorrentId, strconv.FormatInt(id, 10)).Result()
 	return exist, asNil(err)
@@ -201,12 +245,62 @@ func (d database) Close() {
 	}
 }
 
+func (d database) Ping() error {
+	return d.con.Ping(ctx).Err()
+}
+
 func (d database) DelAdmin(id int64) error {
 	return asNil(d.con.SRem(ctx, sAdmin, id).Err())
 }
 
 func (d database) DelChat(chat int64) error {
-	return asNil(d.con.SRem(ctx, sChat, chat).Err())
+	return d.tx(func(tx redis.Pipeliner) error {
+		tx.SRem(ctx, sChat, chat)
+		tx.Del(ctx, sChatSub+strconv.FormatInt(chat, 10))
+		return nil
+	})
+}
+
+func (d database) AddChatSubscription(chat int64, query string) error {
+	return d.con.SAdd(ctx, sChatSub+strconv.FormatInt(chat, 10), query).Err()
+}
+
+// DelChatSubscription removes chat's subscription to query, or all subscriptions if query is empty
+func (d database) DelChatSubscription(chat int64, query string) error {
+	key := sChatSub + strconv.FormatInt(chat, 10)
+	if len(query) == 0 {
+		return asNil(d.con.Del(ctx, key).Err())
+	}
+	return asNil(d.con.SRem(ctx, key, query).Err())
+}
+
+func (d database) GetChatSubscriptions(chat int64) ([]string, error) {
+	out, err := d.con.SMembers(ctx, sChatSub+strconv.FormatInt(chat, 10)).Result()
+	sort.Strings(out)
+	return out, asNil(err)
+}
+
+func (d database) GetSubscriptions() (map[int64][]string, error) {
+	chats, err := d.GetChats()
+	if err != nil || len(chats) == 0 {
+		return nil, err
+	}
+	cmds := make([]*redis.StringSliceCmd, len(chats))
+	if _, err = d.con.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, chat := range chats {
+			cmds[i] = p.SMembers(ctx, sChatSub+strconv.FormatInt(chat, 10))
+		}
+		return nil
+	}); asNil(err) != nil {
+		return nil, err
+	}
+	out := make(map[int64][]string)
+	for i, cmd := range cmds {
+		if queries := cmd.Val(); len(queries) > 0 {
+			out[chats[i]] = queries
+		}
+	}
+	return out, nil
 }
 
 func (d database) GetAdminExist(chat int64) (bool, error) {
@@ -243,6 +337,35 @@ func (d database) GetChatExist(chat int64) (bool, error) {
 	return exist, asNil(err)
 }
 
+func (d database) GetChatLocale(chat int64) (string, error) {
+	out, err := d.con.HGet(ctx, hLocale, strconv.FormatInt(chat, 10)).Result()
+	return out, asNil(err)
+}
+
+func (d database) GetChatLocales() (map[int64]string, error) {
+	vals, err := d.con.HGetAll(ctx, hLocale).Result()
+	if err != nil {
+		return nil, asNil(err)
+	}
+	out := make(map[int64]string, len(vals))
+	for k, v := range vals {
+		chat, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		out[chat] = v
+	}
+	return out, nil
+}
+
+// SetChatLocale sets locale of chat, empty locale resets it to default
+func (d database) SetChatLocale(chat int64, locale string) error {
+	if len(locale) == 0 {
+		return asNil(d.con.HDel(ctx, hLocale, strconv.FormatInt(chat, 10)).Err())
+	}
+	return d.con.HSet(ctx, hLocale, strconv.FormatInt(chat, 10), locale).Err()
+}
+
 func (d database) GetChats() ([]int64, error) {
 	out, err := d.getIntList(sChat)
 	return out, err
@@ -273,6 +396,33 @@ func (d database) GetTorrentImage(id int64) ([]byte, error) {
 	return data, err
 }
 
+func (d database) AddTorrentImages(id int64, images [][]byte) error {
+	key := lTorrentImg + strconv.FormatInt(id, 10)
+	return d.tx(func(tx redis.Pipeliner) error {
+		tx.Del(ctx, key)
+		if l := len(images); l > 0 {
+			ifs := make([]any, l)
+			for i := 0; i < l; i++ {
+				ifs[i] = images[i]
+			}
+			tx.RPush(ctx, key, ifs...)
+		}
+		return nil
+	})
+}
+
+func (d database) GetTorrentImages(id int64) ([][]byte, error) {
+	var images [][]byte
+	res, err := d.con.LRange(ctx, lTorrentImg+strconv.FormatInt(id, 10), 0, -1).Result()
+	if err == nil {
+		images = make([][]byte, len(res))
+		for i, img := range res {
+			images[i] = []byte(img)
+		}
+	}
+	return images, asNil(err)
+}
+
 func (d database) GetTorrentMeta(id int64) (map[string]string, error) {
 	out, err := d.con.HGetAll(ctx, hTorrentMeta+strconv.FormatInt(id, 10)).Result()
 	return out, asNil(err)
@@ -289,6 +439,152 @@ func (d database) GetTorrent(name string) (id int64, err error) {
 	return
 }
 
+func (d database) GetTorrentById(id int64) (t s.DBTorrent, err error) {
+	var hKey string
+	if hKey, err = d.con.HGet(ctx, hTorrentId, strconv.FormatInt(id, 10)).Result(); err == nil {
+		var vals []any
+		if vals, err = d.con.HMGet(ctx, hKey, fName, fURL, fData, fImage).Result(); err == nil {
+			t.Id = id
+			t.Name, _ = vals[0].(string)
+			t.URL, _ = vals[1].(string)
+			if data, ok := vals[2].(string); ok {
+				t.Data = []byte(data)
+			}
+			if image, ok := vals[3].(string); ok {
+				t.Image = []byte(image)
+			}
+		}
+	} else if errors.Is(err, redis.Nil) {
+		err = s.ErrNotFound
+	}
+	return
+}
+
+func likeToGlob(query string) string {
+	sb := strings.Builder{}
+	for _, r := range query {
+		switch r {
+		case '%':
+			sb.WriteRune('*')
+		case '_':
+			sb.WriteRune('?')
+		case '*', '?', '[', ']', '\\':
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// SearchTorrents matches names in name index, so neither other keys nor whole keyspace are scanned
+func (d database) SearchTorrents(query string, offset, limit uint) (out []s.DBTorrent, err error) {
+	iter := d.con.ZScan(ctx, zTorrentName, 0, likeToGlob(query), 0).Iterator()
+	for iter.Next(ctx) {
+		// members are followed by scores
+		t := s.DBTorrent{Name: iter.Val()}
+		if !iter.Next(ctx) {
+			break
+		}
+		if t.Id, err = strconv.ParseInt(iter.Val(), 10, 64); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	if err = iter.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Id > out[j].Id
+	})
+	if offset >= uint(len(out)) {
+		return nil, nil
+	}
+	out = out[offset:]
+	if limit > 0 && limit < uint(len(out)) {
+		out = out[:limit]
+	}
+	for i := range out {
+		if out[i].URL, err = d.con.HGet(ctx, hTorrent+out[i].Name, fURL).Result(); asNil(err) != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// indexNames fills name index of database created before the index was introduced
+func (d database) indexNames() error {
+	if exist, err := d.con.Exists(ctx, zTorrentName).Result(); err != nil || exist > 0 {
+		return err
+	}
+	tMap, err := d.con.HGetAll(ctx, hTorrentId).Result()
+	if err != nil || len(tMap) == 0 {
+		return asNil(err)
+	}
+	names := make([]redis.Z, 0, len(tMap))
+	for sid, hKey := range tMap {
+		id, _ := strconv.ParseInt(sid, 10, 64)
+		names = append(names, redis.Z{Score: float64(id), Member: strings.TrimPrefix(hKey, hTorrent)})
+	}
+	return d.con.ZAdd(ctx, zTorrentName, names...).Err()
+}
+
+func (d database) FindTorrents(query string, offset, limit uint) (out []s.DBTorrent, err error) {
+	query = strings.ToLower(query)
+	var hashes map[string]string
+	if hashes, err = d.con.HGetAll(ctx, hTorrentId).Result(); err != nil {
+		return nil, asNil(err)
+	}
+	var ids []int64
+	for sid, hKey := range hashes {
+		if strings.Contains(strings.ToLower(strings.TrimPrefix(hKey, hTorrent)), query) {
+			if id, idErr := strconv.ParseInt(sid, 10, 64); idErr == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	iter := d.con.Scan(ctx, 0, hTorrentMeta+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		id, idErr := strconv.ParseInt(strings.TrimPrefix(iter.Val(), hTorrentMeta), 10, 64)
+		if idErr != nil || slices.Contains(ids, id) {
+			continue
+		}
+		var values []string
+		if values, err = d.con.HVals(ctx, iter.Val()).Result(); err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(values, func(v string) bool {
+			return strings.Contains(strings.ToLower(v), query)
+		}) {
+			ids = append(ids, id)
+		}
+	}
+	if err = iter.Err(); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(ids, func(a, b int64) int {
+		return cmp.Compare(b, a)
+	})
+	if offset >= uint(len(ids)) {
+		return nil, nil
+	}
+	ids = ids[offset:]
+	if limit > 0 && limit < uint(len(ids)) {
+		ids = ids[:limit]
+	}
+	out = make([]s.DBTorrent, 0, len(ids))
+	for _, id := range ids {
+		var t s.DBTorrent
+		if t, err = d.GetTorrentById(id); err != nil {
+			return nil, err
+		}
+		t.Image = nil
+		out = append(out, t)
+	}
+	return
+}
+
 func (d database) UpdateCrawlOffset(offset uint) error {
 	return d.con.Set(ctx, kConfOffset, offset, 0).Err()
 }
@@ -302,6 +598,9 @@ func (d database) MGetTorrents() (tt []s.DBTorrent, err error) {
 			if t.Name, err = d.con.HGet(ctx, hKey, fName).Result(); asNil(err) != nil {
 				break
 			}
+			if t.URL, err = d.con.HGet(ctx, hKey, fURL).Result(); asNil(err) != nil {
+				break
+			}
 			if t.Data, err = d.con.HGet(ctx, hKey, fData).Bytes(); asNil(err) != nil {
 				break
 			}
@@ -318,7 +617,7 @@ func (d database) MGetTorrents() (tt []s.DBTorrent, err error) {
 func (d database) MPutTorrent(t s.DBTorrent, fs []string) error {
 	return d.tx(func(tx redis.Pipeliner) (err error) {
 		hKey := hTorrent + t.Name
-		if err = d.con.HSet(ctx, hKey, fName, t.Name, fData, t.Data, fImage, t.Image).Err(); err == nil {
+		if err = d.con.HSet(ctx, hKey, fName, t.Name, fURL, t.URL, fData, t.Data, fImage, t.Image).Err(); err == nil {
 			sid := strconv.FormatInt(t.Id, 10)
 			l := len(fs)
 			if l > 0 {
@@ -331,6 +630,9 @@ func (d database) MPutTorrent(t s.DBTorrent, fs []string) error {
 			}
 			if err == nil {
 				if err = d.con.HSet(ctx, hTorrentId, sid, hKey).Err(); err == nil {
+					err = d.con.ZAdd(ctx, zTorrentName, redis.Z{Score: float64(t.Id), Member: t.Name}).Err()
+				}
+				if err == nil {
 					err = d.con.Set(ctx, kTorrentIndex, sid, 0).Err()
 				}
 			}
@@ -338,3 +640,232 @@ func (d database) MPutTorrent(t s.DBTorrent, fs []string) error {
 		return
 	})
 }
+
+func (d database) AddPendingTorrent(p s.PendingTorrent) error {
+	data, err := json.Marshal(p)
+	if err == nil {
+		err = d.con.HSet(ctx, hPending, strconv.FormatInt(p.Id, 10), data).Err()
+	}
+	return err
+}
+
+func (d database) GetPendingTorrents() (out []s.PendingTorrent, err error) {
+	var pMap map[string]string
+	if pMap, err = d.con.HGetAll(ctx, hPending).Result(); err == nil {
+		out = make([]s.PendingTorrent, 0, len(pMap))
+		for _, v := range pMap {
+			var p s.PendingTorrent
+			if err = json.Unmarshal([]byte(v), &p); err != nil {
+				break
+			}
+			out = append(out, p)
+		}
+	}
+	err = asNil(err)
+	return
+}
+
+func (d database) DelPendingTorrent(id int64) error {
+	return asNil(d.con.HDel(ctx, hPending, strconv.FormatInt(id, 10)).Err())
+}
+
+func (d database) AddOutboxJobs(jobs []s.OutboxJob) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+	lastId, err := d.con.IncrBy(ctx, kOutboxId, int64(len(jobs))).Result()
+	if err != nil {
+		return err
+	}
+	return d.tx(func(tx redis.Pipeliner) error {
+		for i, j := range jobs {
+			j.Id = lastId - int64(len(jobs)-i-1)
+			data, err := json.Marshal(j)
+			if err != nil {
+				return err
+			}
+			id := strconv.FormatInt(j.Id, 10)
+			tx.HSet(ctx, hOutbox, id, data)
+			if j.Done.IsZero() {
+				tx.ZAdd(ctx, zOutboxDue, redis.Z{Score: float64(j.NextTry.Unix()), Member: id})
+			}
+		}
+		return nil
+	})
+}
+
+// GetOutboxJobs returns due jobs in order of creation, jobs created after failed job
+// of the same producer, which waits for retry, are held back
+func (d database) GetOutboxJobs(due time.Time, limit uint) ([]s.OutboxJob, error) {
+	ids, err := d.con.ZRangeByScore(ctx, zOutboxDue, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(due.Unix(), 10),
+		Count: int64(limit),
+	}).Result()
+	jobs, err := d.getOutboxJobs(ids, err)
+	if err != nil || len(jobs) == 0 {
+		return jobs, err
+	}
+	later, err := d.GetScheduledOutboxJobs(due)
+	if err != nil {
+		return nil, err
+	}
+	blocked := make(map[string]int64)
+	for _, j := range later {
+		if b, exist := blocked[j.Producer]; len(j.Error) > 0 && (!exist || j.Id < b) {
+			blocked[j.Producer] = j.Id
+		}
+	}
+	jobs = slices.DeleteFunc(jobs, func(j s.OutboxJob) bool {
+		b, exist := blocked[j.Producer]
+		return exist && j.Id > b
+	})
+	slices.SortFunc(jobs, func(a, b s.OutboxJob) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+	return jobs, nil
+}
+
+func (d database) GetScheduledOutboxJobs(after time.Time) ([]s.OutboxJob, error) {
+	ids, err := d.con.ZRangeByScore(ctx, zOutboxDue, &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(after.Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	return d.getOutboxJobs(ids, err)
+}
+
+func (d database) GetDeadOutboxJobs(offset, limit uint) ([]s.OutboxJob, error) {
+	ids, err := d.con.ZRevRange(ctx, zOutboxDead, int64(offset), int64(offset+limit)-1).Result()
+	return d.getOutboxJobs(ids, err)
+}
+
+func (d database) getOutboxJobs(ids []string, err error) ([]s.OutboxJob, error) {
+	var out []s.OutboxJob
+	if err == nil && len(ids) > 0 {
+		var values []any
+		if values, err = d.con.HMGet(ctx, hOutbox, ids...).Result(); err == nil {
+			out = make([]s.OutboxJob, 0, len(values))
+			for _, v := range values {
+				if str, ok := v.(string); ok {
+					var j s.OutboxJob
+					if err = json.Unmarshal([]byte(str), &j); err != nil {
+						break
+					}
+					out = append(out, j)
+				}
+			}
+		}
+	}
+	return out, asNil(err)
+}
+
+func (d database) UpdateOutboxJob(j s.OutboxJob) error {
+	data, err := json.Marshal(j)
+	if err == nil {
+		id := strconv.FormatInt(j.Id, 10)
+		err = d.tx(func(tx redis.Pipeliner) error {
+			tx.HSet(ctx, hOutbox, id, data)
+			if j.Done.IsZero() && !j.Dead {
+				tx.ZAdd(ctx, zOutboxDue, redis.Z{Score: float64(j.NextTry.Unix()), Member: id})
+			} else {
+				tx.ZRem(ctx, zOutboxDue, id)
+			}
+			if j.Dead {
+				tx.ZAdd(ctx, zOutboxDead, redis.Z{Score: float64(j.Id), Member: id})
+			}
+			return nil
+		})
+	}
+	return err
+}
+
+func (d database) RequeueOutboxJob(id int64, next time.Time) error {
+	key := strconv.FormatInt(id, 10)
+	data, err := d.con.HGet(ctx, hOutbox, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return s.ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	var j s.OutboxJob
+	if err = json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if !j.Dead {
+		return s.ErrNotFound
+	}
+	j.Dead, j.Attempts, j.Error, j.NextTry = false, 0, "", next
+	if data, err = json.Marshal(j); err == nil {
+		err = d.tx(func(tx redis.Pipeliner) error {
+			tx.HSet(ctx, hOutbox, key, data)
+			tx.ZRem(ctx, zOutboxDead, key)
+			tx.ZAdd(ctx, zOutboxDue, redis.Z{Score: float64(next.Unix()), Member: key})
+			return nil
+		})
+	}
+	return err
+}
+
+func (d database) PurgeOutbox(before time.Time) error {
+	var err error
+	var cursor uint64
+	for {
+		var kv []string
+		if kv, cursor, err = d.con.HScan(ctx, hOutbox, cursor, "", 100).Result(); err != nil {
+			break
+		}
+		var done []string
+		for i := 0; i+1 < len(kv); i += 2 {
+			var j s.OutboxJob
+			if json.Unmarshal([]byte(kv[i+1]), &j) == nil && !j.Done.IsZero() && j.Done.Before(before) {
+				done = append(done, kv[i])
+			}
+		}
+		if len(done) > 0 {
+			if err = d.con.HDel(ctx, hOutbox, done...).Err(); err != nil {
+				break
+			}
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+	return asNil(err)
+}
+
+func (d database) AddDeliveryRecords(records []s.DeliveryRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	lastId, err := d.con.IncrBy(ctx, kDeliveryId, int64(len(records))).Result()
+	if err != nil {
+		return err
+	}
+	return d.tx(func(tx redis.Pipeliner) error {
+		for i, r := range records {
+			r.Id = lastId - int64(len(records)-i-1)
+			data, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			tx.RPush(ctx, lDelivery+strconv.FormatInt(r.Torrent, 10), data)
+		}
+		return nil
+	})
+}
+
+func (d database) GetDeliveryRecords(torrent int64) ([]s.DeliveryRecord, error) {
+	var out []s.DeliveryRecord
+	res, err := d.con.LRange(ctx, lDelivery+strconv.FormatInt(torrent, 10), 0, -1).Result()
+	if err == nil {
+		out = make([]s.DeliveryRecord, 0, len(res))
+		for _, v := range res {
+			var r s.DeliveryRecord
+			if err = json.Unmarshal([]byte(v), &r); err != nil {
+				break
+			}
+			out = append(out, r)
+		}
+	}
+	return out, asNil(err)
+}
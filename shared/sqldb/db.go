@@ -30,6 +30,8 @@ import (
 	"database/sql"
 	"errors"
 	"strconv"
+	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
@@ -37,6 +39,8 @@ import (
 	s "sot-te.ch/TTObserverV1/shared"
 )
 
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 const (
 	SQLLiteDriver  = "sqlite3"
 	PgDriver       = "postgres"
@@ -48,17 +52,34 @@ const (
 	delChat     = "DELETE FROM TT_CHAT WHERE ID = $1"
 	existChat   = "SELECT TRUE FROM TT_CHAT WHERE ID = $1"
 
+	selectChatSubs = "SELECT QUERY FROM TT_CHAT_SUB WHERE CHAT = $1 ORDER BY QUERY"
+	selectSubs     = "SELECT CHAT, QUERY FROM TT_CHAT_SUB"
+	insertChatSub  = "INSERT INTO TT_CHAT_SUB(CHAT, QUERY) VALUES ($1, $2)"
+	delChatSub     = "DELETE FROM TT_CHAT_SUB WHERE CHAT = $1 AND QUERY = $2"
+	delChatSubs    = "DELETE FROM TT_CHAT_SUB WHERE CHAT = $1"
+	existChatSub   = "SELECT TRUE FROM TT_CHAT_SUB WHERE CHAT = $1 AND QUERY = $2"
+
+	selectChatLocale  = "SELECT LOCALE FROM TT_CHAT_LOCALE WHERE CHAT = $1"
+	selectChatLocales = "SELECT CHAT, LOCALE FROM TT_CHAT_LOCALE"
+	insertChatLocale  = "INSERT INTO TT_CHAT_LOCALE(CHAT, LOCALE) VALUES ($1, $2) ON CONFLICT(CHAT) DO UPDATE SET LOCALE = EXCLUDED.LOCALE"
+	delChatLocale     = "DELETE FROM TT_CHAT_LOCALE WHERE CHAT = $1"
+
 	selectAdmins = "SELECT ID FROM TT_ADMIN"
 	insertAdmin  = "INSERT INTO TT_ADMIN(ID) VALUES ($1)"
 	delAdmin     = "DELETE FROM TT_ADMIN WHERE ID = $1"
 	existAdmin   = "SELECT 1 FROM TT_ADMIN WHERE ID = $1"
 
-	selectTorrents = "SELECT ID, NAME, DATA, IMAGE FROM TT_TORRENT"
+	selectTorrents = "SELECT ID, NAME, COALESCE(URL, ''), DATA, IMAGE FROM TT_TORRENT"
+	searchTorrents = "SELECT ID, NAME, COALESCE(URL, '') FROM TT_TORRENT WHERE NAME LIKE $1 ORDER BY ID DESC LIMIT $2 OFFSET $3"
+	findTorrents   = "SELECT T.ID, T.NAME, COALESCE(T.URL, ''), T.DATA FROM TT_TORRENT T WHERE LOWER(T.NAME) LIKE $1 ESCAPE '\\' " +
+		"OR EXISTS (SELECT 1 FROM TT_TORRENT_META M WHERE M.TORRENT = T.ID AND LOWER(M.VALUE) LIKE $1 ESCAPE '\\') " +
+		"ORDER BY T.ID DESC LIMIT $2 OFFSET $3"
 
 	selectTorrentId       = "SELECT ID FROM TT_TORRENT WHERE NAME = $1"
+	selectTorrentById     = "SELECT ID, NAME, COALESCE(URL, ''), DATA, IMAGE FROM TT_TORRENT WHERE ID = $1"
 	existTorrent          = "SELECT 1 FROM TT_TORRENT WHERE ID = $1"
-	insertTorrent         = "INSERT INTO TT_TORRENT(ID, NAME, DATA, IMAGE) VALUES ($1, $2, $3, $4)"
-	insertOrUpdateTorrent = "INSERT INTO TT_TORRENT(NAME, DATA) VALUES ($1, $2) ON CONFLICT(NAME) DO UPDATE SET DATA = EXCLUDED.DATA"
+	insertTorrent         = "INSERT INTO TT_TORRENT(ID, NAME, URL, DATA, IMAGE) VALUES ($1, $2, $3, $4, $5)"
+	insertOrUpdateTorrent = "INSERT INTO TT_TORRENT(NAME, URL, DATA) VALUES ($1, $2, $3) ON CONFLICT(NAME) DO UPDATE SET URL = EXCLUDED.URL, DATA = EXCLUDED.DATA"
 
 	selectTorrentMeta = "SELECT NAME, VALUE FROM TT_TORRENT_META WHERE TORRENT = $1"
 	insertTorrentMeta = "INSERT INTO TT_TORRENT_META(TORRENT, NAME, VALUE) VALUES($1, $2, $3) ON CONFLICT(TORRENT,NAME) DO UPDATE SET VALUE = EXCLUDED.VALUE"
@@ -69,9 +90,42 @@ const (
 	selectTorrentImage = "SELECT IMAGE FROM TT_TORRENT WHERE ID = $1"
 	insertTorrentImage = "UPDATE TT_TORRENT SET IMAGE = $1 WHERE ID = $2"
 
+	selectTorrentImages = "SELECT IMAGE FROM TT_TORRENT_IMAGE WHERE TORRENT = $1 ORDER BY IDX"
+	insertTorrentImages = "INSERT INTO TT_TORRENT_IMAGE(TORRENT, IDX, IMAGE) VALUES ($1, $2, $3)"
+	delTorrentImages    = "DELETE FROM TT_TORRENT_IMAGE WHERE TORRENT = $1"
+
 	selectConfig         = "SELECT VALUE FROM TT_CONFIG WHERE NAME = $1"
 	insertOrUpdateConfig = "INSERT INTO TT_CONFIG(NAME, VALUE) VALUES ($1, $2) ON CONFLICT(NAME) DO UPDATE SET VALUE = EXCLUDED.VALUE"
 
+	selectTorrentRevisions = "SELECT CREATED FROM TT_TORRENT_REVISION WHERE TORRENT = $1 ORDER BY CREATED"
+	insertTorrentRevision  = "INSERT INTO TT_TORRENT_REVISION(TORRENT, CREATED) VALUES ($1, $2)"
+
+	selectPending         = "SELECT TORRENT, CONTEXT, IS_NEW, SINCE, RETRY, RETRIES, DATA FROM TT_PENDING"
+	insertOrUpdatePending = "INSERT INTO TT_PENDING(TORRENT, CONTEXT, IS_NEW, SINCE, RETRY, RETRIES, DATA) VALUES ($1, $2, $3, $4, $5, $6, $7) " +
+		"ON CONFLICT(TORRENT) DO UPDATE SET CONTEXT = EXCLUDED.CONTEXT, IS_NEW = EXCLUDED.IS_NEW, SINCE = EXCLUDED.SINCE, " +
+		"RETRY = EXCLUDED.RETRY, RETRIES = EXCLUDED.RETRIES, DATA = EXCLUDED.DATA"
+	delPending = "DELETE FROM TT_PENDING WHERE TORRENT = $1"
+
+	// due jobs, which are not held back by earlier failed job of the same producer
+	selectOutbox = "SELECT O.ID, O.TORRENT, O.PRODUCER, O.IS_NEW, O.CREATED, O.NEXT_TRY, O.ATTEMPTS, COALESCE(O.ERROR, ''), O.DONE, O.DEAD, O.PAYLOAD " +
+		"FROM TT_OUTBOX O WHERE O.DONE = 0 AND NOT O.DEAD AND O.NEXT_TRY <= $1 " +
+		"AND NOT EXISTS (SELECT 1 FROM TT_OUTBOX B WHERE B.PRODUCER = O.PRODUCER AND B.ID < O.ID " +
+		"AND B.DONE = 0 AND NOT B.DEAD AND B.NEXT_TRY > $1 AND COALESCE(B.ERROR, '') <> '') ORDER BY O.ID LIMIT $2"
+	selectScheduledOutbox = "SELECT ID, TORRENT, PRODUCER, IS_NEW, CREATED, NEXT_TRY, ATTEMPTS, COALESCE(ERROR, ''), DONE, DEAD, PAYLOAD " +
+		"FROM TT_OUTBOX WHERE DONE = 0 AND NOT DEAD AND NEXT_TRY > $1 ORDER BY ID"
+	selectDeadOutbox = "SELECT ID, TORRENT, PRODUCER, IS_NEW, CREATED, NEXT_TRY, ATTEMPTS, COALESCE(ERROR, ''), DONE, DEAD, PAYLOAD " +
+		"FROM TT_OUTBOX WHERE DEAD ORDER BY ID DESC LIMIT $1 OFFSET $2"
+	insertOutbox = "INSERT INTO TT_OUTBOX(TORRENT, PRODUCER, IS_NEW, CREATED, NEXT_TRY, ATTEMPTS, ERROR, DONE, PAYLOAD) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)"
+	updateOutbox  = "UPDATE TT_OUTBOX SET NEXT_TRY = $1, ATTEMPTS = $2, ERROR = $3, DONE = $4, DEAD = $5, PAYLOAD = $6 WHERE ID = $7"
+	requeueOutbox = "UPDATE TT_OUTBOX SET NEXT_TRY = $1, ATTEMPTS = 0, ERROR = NULL, DEAD = $2 WHERE ID = $3 AND DEAD"
+	purgeOutbox   = "DELETE FROM TT_OUTBOX WHERE DONE > 0 AND DONE < $1"
+
+	selectDeliveries = "SELECT ID, TORRENT, REVISION, PRODUCER, TARGET, MESSAGE_ID, STATUS, COALESCE(ERROR, ''), CREATED " +
+		"FROM TT_DELIVERY WHERE TORRENT = $1 ORDER BY ID"
+	insertDelivery = "INSERT INTO TT_DELIVERY(TORRENT, REVISION, PRODUCER, TARGET, MESSAGE_ID, STATUS, ERROR, CREATED) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"
+
 	confCrawlOffset = "CRAWL_OFFSET"
 )
 
@@ -86,6 +140,7 @@ func init() {
 	})
 	s.RegisterFactory(PgDriver, func(m map[string]any) (db s.Database, err error) {
 		if v, exist := m[dbAddressParam]; exist && v != nil {
+			s.AddDSNSecret(v.(string))
 			db, err = newDb(PgDriver, v.(string))
 		} else {
 			err = s.ErrRequiredParameters
@@ -122,6 +177,10 @@ func (db database) checkConnection() error {
 	return err
 }
 
+func (db database) Ping() error {
+	return db.checkConnection()
+}
+
 func (db database) getNotEmpty(query string, args ...any) (bool, error) {
 	val := false
 	var err error
@@ -187,7 +246,124 @@ func (db database) AddChat(chat int64) error {
 }
 
 func (db database) DelChat(chat int64) error {
-	return db.execNoResult(delChat, chat)
+	err := db.execNoResult(delChat, chat)
+	if err == nil {
+		err = db.execNoResult(delChatSubs, chat)
+	}
+	return err
+}
+
+func (db database) AddChatSubscription(chat int64, query string) error {
+	exist, err := db.getNotEmpty(existChatSub, chat, query)
+	if err == nil && !exist {
+		err = db.execNoResult(insertChatSub, chat, query)
+	}
+	return err
+}
+
+// DelChatSubscription removes chat's subscription to query, or all subscriptions if query is empty
+func (db database) DelChatSubscription(chat int64, query string) error {
+	if len(query) == 0 {
+		return db.execNoResult(delChatSubs, chat)
+	}
+	return db.execNoResult(delChatSub, chat, query)
+}
+
+func (db database) GetChatSubscriptions(chat int64) (out []string, err error) {
+	if err = db.checkConnection(); err == nil {
+		var rows *sql.Rows
+		rows, err = db.con.Query(selectChatSubs, chat)
+		if err == nil && rows != nil {
+			defer rows.Close()
+			for rows.Next() {
+				var query string
+				if err = rows.Scan(&query); err == nil {
+					out = append(out, query)
+				} else {
+					break
+				}
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+		}
+	}
+	return
+}
+
+func (db database) GetSubscriptions() (out map[int64][]string, err error) {
+	if err = db.checkConnection(); err == nil {
+		var rows *sql.Rows
+		rows, err = db.con.Query(selectSubs)
+		if err == nil && rows != nil {
+			defer rows.Close()
+			out = make(map[int64][]string)
+			for rows.Next() {
+				var chat int64
+				var query string
+				if err = rows.Scan(&chat, &query); err == nil {
+					out[chat] = append(out[chat], query)
+				} else {
+					break
+				}
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+		}
+	}
+	return
+}
+
+func (db database) GetChatLocale(chat int64) (string, error) {
+	var locale string
+	err := db.checkConnection()
+	if err == nil {
+		var rows *sql.Rows
+		rows, err = db.con.Query(selectChatLocale, chat)
+		if err == nil && rows != nil {
+			defer rows.Close()
+			if rows.Next() {
+				err = rows.Scan(&locale)
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+		}
+	}
+	return locale, err
+}
+
+func (db database) GetChatLocales() (out map[int64]string, err error) {
+	if err = db.checkConnection(); err == nil {
+		var rows *sql.Rows
+		rows, err = db.con.Query(selectChatLocales)
+		if err == nil && rows != nil {
+			defer rows.Close()
+			out = make(map[int64]string)
+			for rows.Next() {
+				var chat int64
+				var locale string
+				if err = rows.Scan(&chat, &locale); err == nil {
+					out[chat] = locale
+				} else {
+					break
+				}
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+		}
+	}
+	return
+}
+
+// SetChatLocale sets locale of chat, empty locale resets it to default
+func (db database) SetChatLocale(chat int64, locale string) error {
+	if len(locale) == 0 {
+		return db.execNoResult(delChatLocale, chat)
+	}
+	return db.execNoResult(insertChatLocale, chat, locale)
 }
 
 func (db database) GetAdmins() ([]int64, error) {
@@ -233,10 +409,74 @@ func (db database) GetTorrent(torrent string) (torrentId int64, err error) {
 	return torrentId, err
 }
 
-func (db database) AddTorrent(name string, data []byte, files []string) (int64, error) {
+func (db database) GetTorrentById(id int64) (t s.DBTorrent, err error) {
+	if err = db.checkConnection(); err == nil {
+		var rows *sql.Rows
+		rows, err = db.con.Query(selectTorrentById, id)
+		if err == nil && rows != nil {
+			defer rows.Close()
+			if rows.Next() {
+				err = rows.Scan(&t.Id, &t.Name, &t.URL, &t.Data, &t.Image)
+			} else {
+				err = s.ErrNotFound
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+		}
+	}
+	return
+}
+
+func (db database) SearchTorrents(query string, offset, limit uint) (out []s.DBTorrent, err error) {
+	if err = db.checkConnection(); err == nil {
+		var rows *sql.Rows
+		rows, err = db.con.Query(searchTorrents, query, limit, offset)
+		if err == nil && rows != nil {
+			defer rows.Close()
+			for rows.Next() {
+				var t s.DBTorrent
+				if err = rows.Scan(&t.Id, &t.Name, &t.URL); err == nil {
+					out = append(out, t)
+				} else {
+					break
+				}
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+		}
+	}
+	return
+}
+
+func (db database) FindTorrents(query string, offset, limit uint) (out []s.DBTorrent, err error) {
+	pattern := "%" + likeEscaper.Replace(strings.ToLower(query)) + "%"
+	if err = db.checkConnection(); err == nil {
+		var rows *sql.Rows
+		rows, err = db.con.Query(findTorrents, pattern, limit, offset)
+		if err == nil && rows != nil {
+			defer rows.Close()
+			for rows.Next() {
+				var t s.DBTorrent
+				if err = rows.Scan(&t.Id, &t.Name, &t.URL, &t.Data); err == nil {
+					out = append(out, t)
+				} else {
+					break
+				}
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+		}
+	}
+	return
+}
+
+func (db database) AddTorrent(name, url string, data []byte, files []string) (int64, error) {
 	var err error
 	var id int64
-	if err = db.execNoResult(insertOrUpdateTorrent, name, data); err == nil {
+	if err = db.execNoResult(insertOrUpdateTorrent, name, url, data); err == nil {
 		if id, err = db.GetTorrent(name); err == nil {
 			for _, file := range files {
 				err = db.execNoResult(insertTorrentFile, id, file)
@@ -246,6 +486,21 @@ func (db database) AddTorrent(name string, data []byte, files []string) (int64,
 	return id, err
 }
 
+func (db database) AddTorrentRevision(id int64, at time.Time) error {
+	return db.execNoResult(insertTorrentRevision, id, at.Unix())
+}
+
+func (db database) GetTorrentRevisions(id int64) (out []time.Time, err error) {
+	var stamps []int64
+	if stamps, err = db.getIntArray(selectTorrentRevisions, id); err == nil {
+		out = make([]time.Time, 0, len(stamps))
+		for _, st := range stamps {
+			out = append(out, time.Unix(st, 0))
+		}
+	}
+	return
+}
+
 func (db database) GetTorrentFiles(torrent int64) (files []string, err error) {
 	err = db.checkConnection()
 	if err == nil {
@@ -367,6 +622,49 @@ func (db database) AddTorrentImage(id int64, image []byte) error {
 	return db.execNoResult(insertTorrentImage, image, id)
 }
 
+func (db database) GetTorrentImages(id int64) (images [][]byte, err error) {
+	if err = db.checkConnection(); err == nil {
+		var rows *sql.Rows
+		rows, err = db.con.Query(selectTorrentImages, id)
+		if err == nil && rows != nil {
+			defer rows.Close()
+			for rows.Next() {
+				image := make([]byte, 0)
+				if err = rows.Scan(&image); err == nil {
+					images = append(images, image)
+				} else {
+					break
+				}
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+		}
+	}
+	return
+}
+
+func (db database) AddTorrentImages(id int64, images [][]byte) (err error) {
+	if err = db.checkConnection(); err == nil {
+		var tx *sql.Tx
+		if tx, err = db.con.Begin(); err == nil {
+			if _, err = tx.Exec(delTorrentImages, id); err == nil {
+				for i, image := range images {
+					if _, err = tx.Exec(insertTorrentImages, id, i, image); err != nil {
+						break
+					}
+				}
+			}
+			if err == nil {
+				err = tx.Commit()
+			} else {
+				_ = tx.Rollback()
+			}
+		}
+	}
+	return
+}
+
 func (db database) Close() {
 	if db.con != nil {
 		_ = db.con.Close()
@@ -385,7 +683,7 @@ func (db database) MGetTorrents() (out []s.DBTorrent, err error) {
 						Data:  make([]byte, 0),
 						Image: make([]byte, 0),
 					}
-					if err = rows.Scan(&t.Id, &t.Name, &t.Data, &t.Image); err == nil {
+					if err = rows.Scan(&t.Id, &t.Name, &t.URL, &t.Data, &t.Image); err == nil {
 						out = append(out, t)
 					} else {
 						break
@@ -402,7 +700,7 @@ func (db database) MGetTorrents() (out []s.DBTorrent, err error) {
 
 func (db database) MPutTorrent(t s.DBTorrent, files []string) (err error) {
 	if err = db.checkConnection(); err == nil {
-		if err = db.execNoResult(insertTorrent, t.Id, t.Name, t.Data, t.Image); err == nil {
+		if err = db.execNoResult(insertTorrent, t.Id, t.Name, t.URL, t.Data, t.Image); err == nil {
 			for _, f := range files {
 				if err = db.execNoResult(insertTorrentFile, t.Id, f); err != nil {
 					break
@@ -412,3 +710,173 @@ func (db database) MPutTorrent(t s.DBTorrent, files []string) (err error) {
 	}
 	return
 }
+
+func (db database) AddPendingTorrent(p s.PendingTorrent) error {
+	return db.execNoResult(insertOrUpdatePending, p.Id, p.Context, p.IsNew, p.Since.Unix(), p.Retry.Unix(), p.Retries, p.Torrent)
+}
+
+func (db database) GetPendingTorrents() (out []s.PendingTorrent, err error) {
+	if err = db.checkConnection(); err == nil {
+		var rows *sql.Rows
+		rows, err = db.con.Query(selectPending)
+		if err == nil && rows != nil {
+			defer rows.Close()
+			for rows.Next() {
+				var p s.PendingTorrent
+				var since, retry int64
+				if err = rows.Scan(&p.Id, &p.Context, &p.IsNew, &since, &retry, &p.Retries, &p.Torrent); err == nil {
+					p.Since, p.Retry = time.Unix(since, 0), time.Unix(retry, 0)
+					out = append(out, p)
+				} else {
+					break
+				}
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+		}
+	}
+	return
+}
+
+func (db database) DelPendingTorrent(id int64) error {
+	return db.execNoResult(delPending, id)
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func timeOrZero(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+func (db database) AddOutboxJobs(jobs []s.OutboxJob) (err error) {
+	if err = db.checkConnection(); err == nil {
+		var tx *sql.Tx
+		if tx, err = db.con.Begin(); err == nil {
+			for _, j := range jobs {
+				if _, err = tx.Exec(insertOutbox, j.Torrent, j.Producer, j.IsNew, j.Created.Unix(), j.NextTry.Unix(),
+					j.Attempts, j.Error, unixOrZero(j.Done), j.Payload); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = tx.Commit()
+			} else {
+				_ = tx.Rollback()
+			}
+		}
+	}
+	return
+}
+
+func (db database) GetOutboxJobs(due time.Time, limit uint) ([]s.OutboxJob, error) {
+	return db.queryOutbox(selectOutbox, due.Unix(), limit)
+}
+
+func (db database) GetScheduledOutboxJobs(after time.Time) ([]s.OutboxJob, error) {
+	return db.queryOutbox(selectScheduledOutbox, after.Unix())
+}
+
+func (db database) GetDeadOutboxJobs(offset, limit uint) ([]s.OutboxJob, error) {
+	return db.queryOutbox(selectDeadOutbox, limit, offset)
+}
+
+func (db database) queryOutbox(query string, args ...any) (out []s.OutboxJob, err error) {
+	if err = db.checkConnection(); err == nil {
+		var rows *sql.Rows
+		rows, err = db.con.Query(query, args...)
+		if err == nil && rows != nil {
+			defer rows.Close()
+			for rows.Next() {
+				var j s.OutboxJob
+				var created, nextTry, done int64
+				if err = rows.Scan(&j.Id, &j.Torrent, &j.Producer, &j.IsNew, &created, &nextTry, &j.Attempts, &j.Error,
+					&done, &j.Dead, &j.Payload); err == nil {
+					j.Created, j.NextTry, j.Done = time.Unix(created, 0), time.Unix(nextTry, 0), timeOrZero(done)
+					out = append(out, j)
+				} else {
+					break
+				}
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+		}
+	}
+	return
+}
+
+func (db database) UpdateOutboxJob(j s.OutboxJob) error {
+	return db.execNoResult(updateOutbox, j.NextTry.Unix(), j.Attempts, j.Error, unixOrZero(j.Done), j.Dead, j.Payload, j.Id)
+}
+
+func (db database) RequeueOutboxJob(id int64, next time.Time) error {
+	err := db.checkConnection()
+	if err == nil {
+		var res sql.Result
+		if res, err = db.con.Exec(requeueOutbox, next.Unix(), false, id); err == nil {
+			var n int64
+			if n, err = res.RowsAffected(); err == nil && n == 0 {
+				err = s.ErrNotFound
+			}
+		}
+	}
+	return err
+}
+
+func (db database) PurgeOutbox(before time.Time) error {
+	return db.execNoResult(purgeOutbox, before.Unix())
+}
+
+func (db database) AddDeliveryRecords(records []s.DeliveryRecord) (err error) {
+	if err = db.checkConnection(); err == nil {
+		var tx *sql.Tx
+		if tx, err = db.con.Begin(); err == nil {
+			for _, r := range records {
+				if _, err = tx.Exec(insertDelivery, r.Torrent, unixOrZero(r.Revision), r.Producer, r.Target, r.MessageId,
+					r.Status, r.Error, r.Time.Unix()); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = tx.Commit()
+			} else {
+				_ = tx.Rollback()
+			}
+		}
+	}
+	return
+}
+
+func (db database) GetDeliveryRecords(torrent int64) (out []s.DeliveryRecord, err error) {
+	if err = db.checkConnection(); err == nil {
+		var rows *sql.Rows
+		rows, err = db.con.Query(selectDeliveries, torrent)
+		if err == nil && rows != nil {
+			defer rows.Close()
+			for rows.Next() {
+				var r s.DeliveryRecord
+				var revision, created int64
+				if err = rows.Scan(&r.Id, &r.Torrent, &revision, &r.Producer, &r.Target, &r.MessageId, &r.Status, &r.Error,
+					&created); err == nil {
+					r.Revision, r.Time = timeOrZero(revision), time.Unix(created, 0)
+					out = append(out, r)
+				} else {
+					break
+				}
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+		}
+	}
+	return
+}
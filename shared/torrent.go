@@ -39,6 +39,7 @@ import (
 	"net/http"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"crypto/sha256"
 
@@ -47,6 +48,8 @@ import (
 	_ "golang.org/x/image/webp"
 )
 
+const MetaValueSeparator = "\n"
+
 var ErrInvalidImageURL = errors.New("invalid image url")
 
 type TorrentInfo struct {
@@ -54,10 +57,13 @@ type TorrentInfo struct {
 	Name   string
 	URL    string
 	Image  []byte
+	Images [][]byte
 	Meta   map[string]string
 	Files  map[string]bool
 	Data   []byte
 	Length uint64
+	// Revision is time of release revision being announced
+	Revision time.Time
 }
 
 func (t TorrentInfo) NewFiles() []string {
@@ -104,28 +110,7 @@ func GetTorrent(url string) (*TorrentInfo, error) {
 				resp.Close = true
 				defer resp.Body.Close()
 				if data, err = io.ReadAll(resp.Body); err == nil {
-					torrent := new(Torrent)
-					if err = bencode.DecodeBytes(data, torrent); err == nil {
-						res = &TorrentInfo{
-							Name:  torrent.Info.Name,
-							URL:   torrent.PublisherUrl,
-							Files: make(map[string]bool),
-							Data:  data,
-						}
-						if torrent.Info.Files != nil {
-							for _, file := range torrent.Info.Files {
-								if file.Path != nil {
-									allParts := []string{torrent.Info.Name}
-									allParts = append(allParts, file.Path...)
-									res.Files["/"+filepath.Join(allParts...)] = true
-								}
-								res.Length += file.Length
-							}
-						} else {
-							res.Files["/"+torrent.Info.Name] = true
-							res.Length = torrent.Info.Length
-						}
-					}
+					res, err = ParseTorrent(data)
 				}
 			} else {
 				err = buildError(resp, err, "get torrent")
@@ -136,6 +121,34 @@ func GetTorrent(url string) (*TorrentInfo, error) {
 	return res, err
 }
 
+func ParseTorrent(data []byte) (*TorrentInfo, error) {
+	var res *TorrentInfo
+	torrent := new(Torrent)
+	err := bencode.DecodeBytes(data, torrent)
+	if err == nil {
+		res = &TorrentInfo{
+			Name:  torrent.Info.Name,
+			URL:   torrent.PublisherUrl,
+			Files: make(map[string]bool),
+			Data:  data,
+		}
+		if torrent.Info.Files != nil {
+			for _, file := range torrent.Info.Files {
+				if file.Path != nil {
+					allParts := []string{torrent.Info.Name}
+					allParts = append(allParts, file.Path...)
+					res.Files["/"+filepath.Join(allParts...)] = true
+				}
+				res.Length += file.Length
+			}
+		} else {
+			res.Files["/"+torrent.Info.Name] = true
+			res.Length = torrent.Info.Length
+		}
+	}
+	return res, err
+}
+
 func GetTorrentPoster(imageUrl string, maxSize uint) ([]byte, error) {
 	var err error
 	var torrentImage []byte
@@ -0,0 +1,208 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package TTObserver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	s "sot-te.ch/TTObserverV1/shared"
+)
+
+const (
+	trReplace = "replace"
+	trSplit   = "split"
+	trJoin    = "join"
+	trTrim    = "trim"
+	trLower   = "lower"
+	trUpper   = "upper"
+	trTitle   = "title"
+	trMap     = "map"
+	trNumber  = "number"
+	trDate    = "date"
+	trDefault = "default"
+
+	defaultNumberFormat = "%g"
+	defaultDateFormat   = "2006-01-02"
+)
+
+var (
+	errUnknownTransform = errors.New("unknown meta transform action")
+	errDateLayout       = errors.New("date layout (param) not set")
+	errNumberFormat     = errors.New("number format (param) must have one float verb")
+)
+
+type MetaTransform struct {
+	Action string            `json:"action"`
+	Param  string            `json:"param"`
+	Value  string            `json:"value"`
+	Map    map[string]string `json:"map"`
+	re     *regexp.Regexp
+}
+
+type metaTransformer map[string][]MetaTransform
+
+func compileTransforms(conf map[string][]MetaTransform) (metaTransformer, error) {
+	var err error
+	res := make(metaTransformer, len(conf))
+	for field, chain := range conf {
+		compiled := make([]MetaTransform, len(chain))
+		for i, t := range chain {
+			switch t.Action {
+			case trReplace:
+				t.re, err = regexp.Compile(t.Param)
+			case trNumber:
+				if len(t.Param) > 0 && strings.Contains(fmt.Sprintf(t.Param, 1.5), "%!") {
+					err = errNumberFormat
+				}
+			case trDate:
+				if len(t.Param) == 0 {
+					err = errDateLayout
+				}
+			case trSplit, trJoin, trTrim, trLower, trUpper, trTitle, trMap, trDefault:
+			default:
+				err = errUnknownTransform
+			}
+			if err != nil {
+				return nil, fmt.Errorf("meta transform %s #%d (%s): %w", field, i, t.Action, err)
+			}
+			compiled[i] = t
+		}
+		res[field] = compiled
+	}
+	return res, nil
+}
+
+func (mt metaTransformer) apply(meta map[string]string) error {
+	var errs []error
+	for field, chain := range mt {
+		if v, err := transformValue(meta[field], chain); err == nil {
+			meta[field] = v
+		} else {
+			errs = append(errs, fmt.Errorf("meta transform %s: %w", field, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func transformValue(value string, chain []MetaTransform) (string, error) {
+	var err error
+	values := []string{value}
+	for _, t := range chain {
+		switch t.Action {
+		case trSplit:
+			split := make([]string, 0, len(values))
+			for _, v := range values {
+				for _, e := range strings.Split(v, t.Param) {
+					if e = strings.TrimSpace(e); len(e) > 0 {
+						split = append(split, e)
+					}
+				}
+			}
+			values = split
+		case trJoin:
+			values = []string{strings.Join(values, t.Param)}
+		case trDefault:
+			if len(values) == 0 || len(values) == 1 && len(values[0]) == 0 {
+				values = []string{t.Param}
+			}
+		default:
+			for i, v := range values {
+				if values[i], err = transformElement(v, t); err != nil {
+					return value, err
+				}
+			}
+		}
+	}
+	return strings.Join(values, s.MetaValueSeparator), nil
+}
+
+func transformElement(v string, t MetaTransform) (string, error) {
+	var err error
+	switch t.Action {
+	case trReplace:
+		v = t.re.ReplaceAllString(v, t.Value)
+	case trTrim:
+		if len(t.Param) == 0 {
+			v = strings.TrimSpace(v)
+		} else {
+			v = strings.Trim(v, t.Param)
+		}
+	case trLower:
+		v = strings.ToLower(v)
+	case trUpper:
+		v = strings.ToUpper(v)
+	case trTitle:
+		v = toTitle(v)
+	case trMap:
+		if mapped, ok := t.Map[v]; ok {
+			v = mapped
+		} else if len(t.Value) > 0 {
+			v = t.Value
+		}
+	case trNumber:
+		if len(v) > 0 {
+			var n float64
+			num := strings.ReplaceAll(strings.Join(strings.Fields(v), ""), ",", ".")
+			if n, err = strconv.ParseFloat(num, 64); err == nil {
+				format := t.Param
+				if len(format) == 0 {
+					format = defaultNumberFormat
+				}
+				v = fmt.Sprintf(format, n)
+			}
+		}
+	case trDate:
+		if len(v) > 0 {
+			var d time.Time
+			if d, err = time.Parse(t.Param, v); err == nil {
+				format := t.Value
+				if len(format) == 0 {
+					format = defaultDateFormat
+				}
+				v = d.Format(format)
+			}
+		}
+	}
+	return v, err
+}
+
+func toTitle(v string) string {
+	rs, isStart := []rune(strings.ToLower(v)), true
+	for i, r := range rs {
+		if isStart && unicode.IsLetter(r) {
+			rs[i] = unicode.ToTitle(r)
+		}
+		isStart = unicode.IsSpace(r)
+	}
+	return string(rs)
+}
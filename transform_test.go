@@ -0,0 +1,141 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package TTObserver
+
+import (
+	"errors"
+	"maps"
+	"testing"
+)
+
+func TestMetaTransformerApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		conf  map[string][]MetaTransform
+		meta  map[string]string
+		want  map[string]string
+		fails bool
+	}{
+		{
+			name: "replace",
+			conf: map[string][]MetaTransform{"size": {{Action: trReplace, Param: `\s*GB$`, Value: ""}}},
+			meta: map[string]string{"size": "1.5 GB"},
+			want: map[string]string{"size": "1.5"},
+		},
+		{
+			name: "replace to empty",
+			conf: map[string][]MetaTransform{"year": {{Action: trReplace, Param: `^unknown$`, Value: ""}}},
+			meta: map[string]string{"year": "unknown"},
+			want: map[string]string{"year": ""},
+		},
+		{
+			name: "default after empty",
+			conf: map[string][]MetaTransform{"year": {{Action: trReplace, Param: `^unknown$`, Value: ""}, {Action: trDefault, Param: "-"}}},
+			meta: map[string]string{"year": "unknown"},
+			want: map[string]string{"year": "-"},
+		},
+		{
+			name: "default of missing field",
+			conf: map[string][]MetaTransform{"genre": {{Action: trDefault, Param: "other"}}},
+			meta: map[string]string{},
+			want: map[string]string{"genre": "other"},
+		},
+		{
+			name: "split map join",
+			conf: map[string][]MetaTransform{"genre": {
+				{Action: trSplit, Param: ","},
+				{Action: trTitle},
+				{Action: trMap, Map: map[string]string{"Scifi": "Sci-Fi"}},
+				{Action: trJoin, Param: ", "},
+			}},
+			meta: map[string]string{"genre": "scifi, drama ,, comedy"},
+			want: map[string]string{"genre": "Sci-Fi, Drama, Comedy"},
+		},
+		{
+			name: "split keeps separator",
+			conf: map[string][]MetaTransform{"tags": {{Action: trSplit, Param: ";"}, {Action: trUpper}}},
+			meta: map[string]string{"tags": "a;b"},
+			want: map[string]string{"tags": "A\nB"},
+		},
+		{
+			name: "number and date",
+			conf: map[string][]MetaTransform{
+				"rating": {{Action: trNumber, Param: "%.1f"}},
+				"date":   {{Action: trDate, Param: "02.01.2006"}},
+			},
+			meta: map[string]string{"rating": "7,25", "date": "31.12.2024"},
+			want: map[string]string{"rating": "7.2", "date": "2024-12-31"},
+		},
+		{
+			name:  "invalid number keeps value",
+			conf:  map[string][]MetaTransform{"rating": {{Action: trNumber}}},
+			meta:  map[string]string{"rating": "n/a"},
+			want:  map[string]string{"rating": "n/a"},
+			fails: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt, err := compileTransforms(tt.conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = mt.apply(tt.meta)
+			if (err != nil) != tt.fails {
+				t.Errorf("apply() error = %v, want error %v", err, tt.fails)
+			}
+			if !maps.Equal(tt.meta, tt.want) {
+				t.Errorf("apply() = %q, want %q", tt.meta, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileTransforms(t *testing.T) {
+	tests := []struct {
+		name string
+		t    MetaTransform
+		want error
+	}{
+		{"number default", MetaTransform{Action: trNumber}, nil},
+		{"number float", MetaTransform{Action: trNumber, Param: "%.1f GB"}, nil},
+		{"number int verb", MetaTransform{Action: trNumber, Param: "%d"}, errNumberFormat},
+		{"number no verb", MetaTransform{Action: trNumber, Param: "GB"}, errNumberFormat},
+		{"number two verbs", MetaTransform{Action: trNumber, Param: "%g %g"}, errNumberFormat},
+		{"date", MetaTransform{Action: trDate, Param: "02.01.2006"}, nil},
+		{"date without layout", MetaTransform{Action: trDate}, errDateLayout},
+		{"unknown", MetaTransform{Action: "reverse"}, errUnknownTransform},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileTransforms(map[string][]MetaTransform{"f": {tt.t}})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("compileTransforms() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+/*
+ * BSD-3-Clause
+ * Copyright 2026 sot (aka PR_713, C_rho_272)
+ * Redistribution and use in source and binary forms, with or without modification,
+ * are permitted provided that the following conditions are met:
+ * 1. Redistributions of source code must retain the above copyright notice,
+ * this list of conditions and the following disclaimer.
+ * 2. Redistributions in binary form must reproduce the above copyright notice,
+ * this list of conditions and the following disclaimer in the documentation and/or
+ * other materials provided with the distribution.
+ * 3. Neither the name of the copyright holder nor the names of its contributors
+ * may be used to endorse or promote products derived from this software without
+ * specific prior written permission.
+ *
+ * THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ * ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+ * WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+ * IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT,
+ * INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+ * BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA,
+ * OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY,
+ * WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ * ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY
+ * OF SUCH DAMAGE.
+ */
+
+package TTObserver
+
+import (
+	"crypto/subtle"
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"sot-te.ch/TTObserverV1/producer"
+	s "sot-te.ch/TTObserverV1/shared"
+)
+
+const webPageSize = 20
+
+//go:embed web/*.html
+var webFS embed.FS
+
+var webTemplates = template.Must(template.ParseFS(webFS, "web/*.html"))
+
+type webRelease struct {
+	s.DBTorrent
+	Revision time.Time
+}
+
+type webIndex struct {
+	Title, Query, Error string
+	Releases            []webRelease
+	Page                uint
+	PrevPage, NextPage  uint
+	HasNext             bool
+}
+
+type webReleaseView struct {
+	Title      string
+	Size       string
+	Release    *s.TorrentInfo
+	Files      []string
+	Gallery    []int
+	Revisions  []time.Time
+	Deliveries []producer.Delivery
+	Ledger     []s.DeliveryRecord
+}
+
+func (cr *Observer) webAuthorized(r *http.Request) bool {
+	if user, password, ok := r.BasicAuth(); ok {
+		if expected, exist := cr.Web.Users[user]; exist {
+			return subtle.ConstantTimeCompare([]byte(expected), []byte(password)) == 1
+		}
+	}
+	return false
+}
+
+func (cr *Observer) webHandler(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cr.webAuthorized(r) {
+			fn(w, r)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Basic realm="TTObserver"`)
+			http.Error(w, errUnauthorized.Error(), http.StatusUnauthorized)
+		}
+	}
+}
+
+func renderPage(w http.ResponseWriter, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := webTemplates.ExecuteTemplate(w, name, data); err != nil {
+		logger.Warning(err)
+	}
+}
+
+func webError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, s.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, s.ErrRequiredParameters):
+		status = http.StatusBadRequest
+	case errors.Is(err, errNotInitialized):
+		status = http.StatusServiceUnavailable
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func (cr *Observer) webIndex(w http.ResponseWriter, r *http.Request) {
+	page := webIndex{
+		Title: "Releases",
+		Query: r.URL.Query().Get("q"),
+		Page:  queryUint(r, "page", 0),
+	}
+	query := page.Query
+	if len(query) == 0 {
+		query = "%"
+	}
+	db, _, err := cr.state()
+	var torrents []s.DBTorrent
+	if err == nil {
+		torrents, err = db.SearchTorrents(query, page.Page*webPageSize, webPageSize+1)
+	}
+	if err == nil {
+		if page.HasNext = len(torrents) > webPageSize; page.HasNext {
+			torrents = torrents[:webPageSize]
+		}
+		page.Releases = make([]webRelease, 0, len(torrents))
+		for _, t := range torrents {
+			rel := webRelease{DBTorrent: t}
+			if revisions, revErr := db.GetTorrentRevisions(t.Id); revErr == nil && len(revisions) > 0 {
+				rel.Revision = revisions[len(revisions)-1]
+			}
+			page.Releases = append(page.Releases, rel)
+		}
+	} else {
+		page.Error = err.Error()
+	}
+	if page.Page > 0 {
+		page.PrevPage = page.Page - 1
+	}
+	page.NextPage = page.Page + 1
+	renderPage(w, "index.html", page)
+}
+
+func (cr *Observer) webRelease(w http.ResponseWriter, r *http.Request) {
+	id, err := pathId(r)
+	var db s.Database
+	var announcer *producer.Announcer
+	if err == nil {
+		db, announcer, err = cr.state()
+	}
+	var torrent *s.TorrentInfo
+	if err == nil {
+		torrent, err = cr.LoadTorrent(id)
+	}
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	view := webReleaseView{
+		Title:      torrent.Name,
+		Size:       producer.FormatFileSize(torrent.Length),
+		Release:    torrent,
+		Files:      make([]string, 0, len(torrent.Files)),
+		Gallery:    make([]int, 0, len(torrent.Images)),
+		Deliveries: announcer.Deliveries(id),
+	}
+	for f := range torrent.Files {
+		view.Files = append(view.Files, f)
+	}
+	sort.Strings(view.Files)
+	for i := range torrent.Images {
+		view.Gallery = append(view.Gallery, i+1)
+	}
+	if view.Revisions, err = db.GetTorrentRevisions(id); err != nil {
+		logger.Warning(err)
+	}
+	if view.Ledger, err = db.GetDeliveryRecords(id); err != nil {
+		logger.Warning(err)
+	}
+	renderPage(w, "release.html", view)
+}
+
+func (cr *Observer) webImage(w http.ResponseWriter, r *http.Request) {
+	id, err := pathId(r)
+	var n int
+	if err == nil {
+		if n, err = strconv.Atoi(r.PathValue("n")); err != nil || n < 0 {
+			err = s.ErrRequiredParameters
+		}
+	}
+	var db s.Database
+	if err == nil {
+		db, _, err = cr.state()
+	}
+	var image []byte
+	if err == nil {
+		if n == 0 {
+			image, err = db.GetTorrentImage(id)
+		} else {
+			var images [][]byte
+			if images, err = db.GetTorrentImages(id); err == nil && n <= len(images) {
+				image = images[n-1]
+			}
+		}
+	}
+	if err == nil && len(image) == 0 {
+		err = s.ErrNotFound
+	}
+	if err == nil {
+		w.Header().Set("Content-Type", http.DetectContentType(image))
+		w.Header().Set("Cache-Control", "max-age=3600")
+		_, _ = w.Write(image)
+	} else {
+		webError(w, err)
+	}
+}
+
+func (cr *Observer) StartWeb() *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", cr.webHandler(cr.webIndex))
+	mux.HandleFunc("GET /release/{id}", cr.webHandler(cr.webRelease))
+	mux.HandleFunc("GET /image/{id}/{n}", cr.webHandler(cr.webImage))
+	srv := &http.Server{
+		Addr:              cr.Web.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		logger.Info("Starting web UI on ", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(err)
+		}
+	}()
+	return srv
+}